@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/tools"
+)
+
+// ActionItemsSection collects the follow-ups found in meeting notes. It is
+// written by the notes watcher rather than the model, and is left alone by
+// refreshes.
+var ActionItemsSection = Section{
+	Name:    "action_items",
+	Title:   "Action items",
+	Heading: "## Action items 📌",
+}
+
+type ActionItem struct {
+	Owner  string `json:"owner"`
+	Action string `json:"action"`
+}
+
+// NewNotesAgent creates an agent that reads meeting notes. It has no tools,
+// everything it needs is in the notes.
+func NewNotesAgent(provider Provider, config *config.Config) *Agent {
+	return newAgent(provider, tools.ToolCalls{}, config, config.GetSectionContextLocation(ActionItemsSection.Name))
+}
+
+// ExtractActionItems asks the model for the action items in a meeting's notes.
+func (a *Agent) ExtractActionItems(ctx context.Context, meeting string, notes string) ([]ActionItem, error) {
+	a.contextManager.Clear()
+	a.appendIntroduction()
+	a.contextManager.AppendUserMessage("These are my notes from the meeting \"" + meeting + "\":\n" + notes)
+	a.contextManager.AppendUserMessage(`List the action items in the notes, with who owns each one. Use my name when the owner is me or is not clear.
+Only return a JSON array such as [{"owner": "name", "action": "what needs doing"}], or [] if there are none.`)
+	reply, err := a.callModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Models like to wrap JSON in a code fence
+	reply = strings.TrimSpace(reply)
+	reply = strings.TrimPrefix(reply, "```json")
+	reply = strings.TrimPrefix(reply, "```")
+	reply = strings.TrimSuffix(reply, "```")
+
+	var items []ActionItem
+	if err := json.Unmarshal([]byte(strings.TrimSpace(reply)), &items); err != nil {
+		return nil, fmt.Errorf("error reading action items: %v", err)
+	}
+	return items, nil
+}
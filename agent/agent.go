@@ -2,24 +2,38 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/document"
 	"github.com/gabe-mason/good-morning/tools"
 )
 
+const (
+	// contextWindow is how many tokens Claude 3.5 Sonnet reads and writes in
+	// a request
+	contextWindow = 200000
+	// maxOutputTokens is the longest reply Claude 3.5 Sonnet writes
+	maxOutputTokens = 8192
+)
+
 type Agent struct {
 	contextManager *ContextManager
 	systemPrompt   string
-	client         anthropic.Client
+	provider       Provider
 	tools          tools.ToolCalls
 	maxRetries     int
 	state          AgentState
-	contextWindow  int
-	modelName      string
 	config         *config.Config
+	writer         *tools.MarkdownWriter
+	builder        *document.Builder
+	headings       []string
+	summary        []Section
 }
 
 type AgentState struct {
@@ -27,97 +41,180 @@ type AgentState struct {
 	ConversationActive bool
 }
 
-func NewAgent(client anthropic.Client, tools tools.ToolCalls, config *config.Config) *Agent {
-	contextWindow := 8192 // Default max size for Claude 3.5 Sonnet
+// NewAgent creates an agent for the daily summary. It writes the summary
+// section by section through the markdown_writer tool.
+func NewAgent(provider Provider, toolCalls tools.ToolCalls, config *config.Config) *Agent {
+	agent := newAgent(provider, toolCalls, config, config.GetContextManagerLocation())
+	agent.summary = summarySections(config.WeekAhead(time.Now()))
+	agent.headings = append([]string{"# Good Morning " + config.MyName + "!"}, SummaryHeadings(agent.summary)...)
+	return agent
+}
+
+// NewSectionAgent creates an agent that refreshes a single section. It keeps
+// its own context file so it does not disturb the day's main conversation.
+func NewSectionAgent(provider Provider, tools tools.ToolCalls, config *config.Config, section Section) *Agent {
+	return newAgent(provider, tools, config, config.GetSectionContextLocation(section.Name))
+}
+
+func newAgent(provider Provider, tools tools.ToolCalls, config *config.Config, contextLocation string) *Agent {
 	contextManager := CreateContextManager(
 		tools,
-		contextWindow,
-		config.GetContextManagerLocation(),
+		contextLocation,
 	)
 
-	return &Agent{
+	agent := &Agent{
 		systemPrompt: `You are an AI agent that can use tools to produce a daily summary of my day.
 		Be concise and to the point, do not include any other text.
 		You must only return markdown formatted text.`,
 		contextManager: contextManager,
-		client:         client,
+		provider:       provider,
 		tools:          tools,
 		maxRetries:     3,
 		state: AgentState{
 			ConversationActive: true,
 			metadata:           make(map[string]interface{}),
 		},
-		contextWindow: contextWindow,
-		modelName:     anthropic.ModelClaude3_5SonnetLatest,
-		config:        config,
+		config: config,
 	}
+	contextManager.SetSummariser(agent.summariseToolResult)
+
+	return agent
 }
 
+// GenerateDailySummary returns the summary assembled from the sections the
+// model wrote, falling back to its final reply if it wrote none.
 func (a *Agent) GenerateDailySummary(ctx context.Context) (string, error) {
-	a.contextManager.AppendUserMessage("The current date is " + time.Now().Format("2006-01-02"))
-	a.contextManager.AppendUserMessage("My name is " + a.config.MyName + " and I'm an engineer in teams " + a.config.LinearTeams)
-	a.contextManager.AppendUserMessage("What's the plan for today? Check my calendar for meetings and Linear for any issues I need to review or work on. Include Zoom links or Linear links if they exist.  I like emojis, please use them.")
-	a.contextManager.AppendUserMessage("Create a section for each meeting I have, include a note of the people in attendance and the topic of the meeting, with some space for notes.")
-	a.contextManager.AppendUserMessage("Create a section for each issue I need to review for my team, is is everyone except me, include a note of the title, author, and priority of the issue with a link to the issue, do not redact.")
-	a.contextManager.AppendUserMessage("Create a section for each thing I need to do, include a note of the title, author, and priority of the issue with a link to the issue.")
-	a.contextManager.AppendUserMessage("Create a markdown formatted with the following gist")
-	a.contextManager.AppendUserMessage(`Start each file with some interesting ASCII art max 8 x 8 characters.
-{ascii art}
-# Good Morning {name}!
-{tell me a joke}
-{any comments that you have put them here}
-## Calendar 📅
-
-### {emoji representing meeting type} {time} | {meeting title}
-- **Attendees**: {attendees}
-- **Topic**: {meeting topic}
-- **Zoom**: {zoom link}
+	// Each summary gets its own writer and builder, as the sections channel is
+	// closed once the summary is done
+	sections := make(chan tools.Section)
+	a.writer = tools.NewMarkdownWriter(sections)
+	a.builder = document.NewBuilder(a.headings...)
+	toolCalls := a.tools
+	// Copy the tools so agents sharing them do not share the writer
+	a.tools = append(toolCalls[:len(toolCalls):len(toolCalls)], a.writer)
+	defer func() { a.tools = toolCalls }()
 
-#### Notes:
-- 
+	go a.builder.Consume(sections)
+	reply, err := a.generateDailySummary(ctx)
+	close(sections)
+	if err != nil {
+		return "", err
+	}
+	if a.builder.Empty() {
+		return reply, nil
+	}
+	return a.builder.Render(), nil
+}
 
-## Things I need to review 👀
+func (a *Agent) generateDailySummary(ctx context.Context) (string, error) {
+	if a.contextManager.Interrupted() {
+		fmt.Println("Picking up where I left off.")
+		return a.resume(ctx)
+	}
+	a.contextManager.Clear()
+	a.appendIntroduction()
+	a.contextManager.AppendUserMessage("What's the plan for today? Check my calendar for meetings and Linear for any issues I need to review or work on. Check GitHub for pull requests waiting on my review, pull requests I approved that changed since, my pull requests with failing checks or changes requested, issues assigned to me and my unread notifications. Include video call links or Linear links if they exist.  I like emojis, please use them.")
+	for _, section := range a.summary {
+		a.contextManager.AppendUserMessage(section.Prompt)
+	}
+	a.contextManager.AppendUserMessage("Write the summary with the markdown_writer tool, with one section for each of these titles in this order: " + strings.Join(summaryTitles(a.summary), ", ") + ". Do not include the heading in the content of a section. Once every section is written reply with done.")
+	a.contextManager.AppendUserMessage("Write the sections with the following gist")
+	a.contextManager.AppendUserMessage(summaryTemplate(a.summary))
+	return a.callModel(ctx)
+}
 
-n items need review from my team:
+// OwnedHeadings are the headings of the sections the summary replaces in an
+// existing file, including the week ahead when it is in this briefing.
+func (a *Agent) OwnedHeadings() []string {
+	headings := make([]string, 0, len(OwnedSections)+1)
+	for _, section := range a.summary {
+		owned := section.Name == WeekAheadSection.Name || slices.ContainsFunc(OwnedSections, func(s Section) bool { return s.Name == section.Name })
+		if owned {
+			headings = append(headings, section.Heading)
+		}
+	}
+	return headings
+}
 
-1. [task identifier](link) - title (assigned to)
+// Headings are the headings of the summary's sections in the order it renders
+// them.
+func (a *Agent) Headings() []string {
+	return SummaryHeadings(a.summary)
+}
 
-## Things I need to do ✅
+// RefreshSection regenerates a single section of the daily summary, starting
+// with its heading.
+func (a *Agent) RefreshSection(ctx context.Context, section Section) (string, error) {
+	a.contextManager.Clear()
+	a.appendIntroduction()
+	a.contextManager.AppendUserMessage(section.Prompt)
+	a.contextManager.AppendUserMessage("Only create the \"" + section.Heading + "\" section of my daily summary, start with the heading and do not include any other sections. Use the following gist")
+	a.contextManager.AppendUserMessage(section.Template)
+	return a.callModel(ctx)
+}
 
-Active issues assigned to you:
+// resume continues a conversation loaded from an earlier run. Tool results
+// already in the conversation are reused, only the calls that never got a
+// result are run again.
+func (a *Agent) resume(ctx context.Context) (string, error) {
+	a.replaySections(ctx)
+	if pending := a.contextManager.PendingToolUses(); len(pending) > 0 {
+		toolResults := make([]anthropic.ContentBlockParamUnion, 0, len(pending))
+		for _, block := range pending {
+			toolResponse, err := a.processToolUseBlock(ctx, block)
+			if err != nil {
+				return "", err
+			}
+			toolResults = append(toolResults, toolResponse)
+		}
+		a.contextManager.AppendToolResults(toolResults)
+	}
+	return a.callModel(ctx)
+}
 
-**High Priority**:
-- (emoji representing priority) [task identifier](link) - title(status)
+// replaySections feeds the sections written before the run was interrupted to
+// the builder, as those tool calls are not run again.
+func (a *Agent) replaySections(ctx context.Context) {
+	if a.writer == nil {
+		return
+	}
+	for _, block := range a.contextManager.CompletedToolUses(a.writer.Name()) {
+		input, err := json.Marshal(block.Input)
+		if err != nil {
+			continue
+		}
+		// Failed writes were reported to the model at the time
+		a.writer.Run(ctx, input)
+	}
+}
 
-**In Progress**:
-- (emoji representing priority) [task identifier](link) - title(status)
+func (a *Agent) appendIntroduction() {
+	a.contextManager.AppendUserMessage("The current date is " + time.Now().In(a.config.Location).Format("2006-01-02"))
+	a.contextManager.AppendUserMessage("My name is " + a.config.MyName + " and I'm an engineer in teams " + a.config.LinearTeams)
+}
 
-**To Do**:
-- (emoji representing priority) [task identifier](link) - title(status)
-## Suggestions 💡
--- Add suggestions for my day here
-`)
-	summary, err := a.callModel(ctx)
-	if err != nil {
-		return "", err
+// inputBudget is how many tokens of the context window the conversation may
+// use, leaving room for the reply, the system prompt and the tool definitions.
+func (a *Agent) inputBudget() int {
+	reserved := maxOutputTokens + a.contextManager.countTokens(a.systemPrompt)
+	for _, tool := range a.tools {
+		if definition, err := json.Marshal(tool.ToolDefinition()); err == nil {
+			reserved += a.contextManager.countTokens(string(definition))
+		}
 	}
-
-	return summary, nil
+	return contextWindow - reserved
 }
 
 func (a *Agent) callModel(ctx context.Context) (string, error) {
 	for a.contextManager.HasNewMessages() {
-		response, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
-			MaxTokens: int64(a.contextWindow),
+		if err := a.contextManager.Compact(ctx, a.inputBudget()); err != nil {
+			return "", err
+		}
+		response, err := a.provider.Complete(ctx, CompletionRequest{
+			System:    a.systemPrompt,
 			Messages:  a.contextManager.GetMessages(),
-			Model:     a.modelName,
-			Tools:     a.tools.GetToolDefinitions(),
-			System: []anthropic.TextBlockParam{
-				{
-					Type: "text",
-					Text: a.systemPrompt,
-				},
-			},
+			Tools:     a.tools,
+			MaxTokens: maxOutputTokens,
 		})
 		a.contextManager.ClearNewMessages()
 		if err != nil {
@@ -146,7 +243,8 @@ func (a *Agent) processResponse(ctx context.Context, response *anthropic.Message
 			}
 			return block.Text, nil
 		case anthropic.ToolUseBlock:
-			toolResponse, err := a.processToolUseBlock(ctx, &block)
+			toolUse := block.ToParam()
+			toolResponse, err := a.processToolUseBlock(ctx, &toolUse)
 			if err != nil {
 				return "", err
 			}
@@ -157,8 +255,11 @@ func (a *Agent) processResponse(ctx context.Context, response *anthropic.Message
 	return "", nil
 }
 
-func (a *Agent) processToolUseBlock(ctx context.Context, block *anthropic.ToolUseBlock) (anthropic.ContentBlockParamUnion, error) {
-	input := block.Input
+func (a *Agent) processToolUseBlock(ctx context.Context, block *anthropic.ToolUseBlockParam) (anthropic.ContentBlockParamUnion, error) {
+	input, err := json.Marshal(block.Input)
+	if err != nil {
+		return anthropic.NewToolResultBlock(block.ID, "The tool input could not be read.", true), nil
+	}
 	tool, err := a.tools.GetTool(block.Name)
 	fmt.Println("Going to have a chin wag with " + block.Name + ".")
 	if err != nil {
@@ -174,3 +275,27 @@ func (a *Agent) processToolUseBlock(ctx context.Context, block *anthropic.ToolUs
 	fmt.Println(block.Name + " has answered my questions.")
 	return anthropic.NewToolResultBlock(block.ID, toolResult, false), nil
 }
+
+// summariseToolResult is a side call to the model that condenses an old tool
+// result so it can stay in the conversation without the full payload.
+func (a *Agent) summariseToolResult(ctx context.Context, toolName string, content string) (string, error) {
+	fmt.Println("Tidying up what " + toolName + " told me earlier.")
+	response, err := a.provider.Complete(ctx, CompletionRequest{
+		System: `You summarise tool results for another AI agent that is writing a daily summary.
+		Keep every identifier, title, time, person, status, priority and link.
+		Drop everything else. Only return the summary.`,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("Summarise this result from the " + toolName + " tool:\n" + content)),
+		},
+		MaxTokens: 1024,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, block := range response.Content {
+		if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+			return text.Text, nil
+		}
+	}
+	return "", fmt.Errorf("summary response had no text")
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/tools"
+)
+
+// writingProvider writes the next of its calendars with the markdown_writer
+// tool, then replies done.
+type writingProvider struct {
+	calendars []string
+	calls     int
+}
+
+func (p *writingProvider) Complete(ctx context.Context, request CompletionRequest) (*anthropic.Message, error) {
+	p.calls++
+	reply := `{"role":"assistant","stop_reason":"end_turn","content":[{"type":"text","text":"done"}]}`
+	if p.calls%2 == 1 {
+		input, err := json.Marshal(map[string]any{"sections": []map[string]any{{
+			"title":   "Calendar",
+			"content": p.calendars[p.calls/2],
+		}}})
+		if err != nil {
+			return nil, err
+		}
+		reply = `{"role":"assistant","stop_reason":"tool_use","content":[{"type":"tool_use","id":"write","name":"markdown_writer","input":` + string(input) + `}]}`
+	}
+	var message anthropic.Message
+	if err := json.Unmarshal([]byte(reply), &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func TestGenerateDailySummaryTwice(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{GoodMorningRoot: "good-morning", MyName: "Ada", Location: time.UTC}
+	provider := &writingProvider{calendars: []string{"- 09:00 Standup", "- 10:00 Planning"}}
+	agent := NewAgent(provider, nil, cfg)
+
+	for _, want := range provider.calendars {
+		summary, err := agent.GenerateDailySummary(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(summary, "## Calendar 📅\n\n"+want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+	if got := len(agent.tools); got != 0 {
+		t.Errorf("agent kept %d tools, want the writer removed after each summary", got)
+	}
+}
+
+func TestInputBudget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{GoodMorningRoot: "good-morning", MyName: "Ada", Location: time.UTC}
+	withoutTools := NewAgent(&writingProvider{}, nil, cfg).inputBudget()
+	withTools := NewAgent(&writingProvider{}, tools.ToolCalls{tools.NewMarkdownWriter(nil)}, cfg).inputBudget()
+
+	if withoutTools >= contextWindow-maxOutputTokens {
+		t.Errorf("budget is %d, want room for the reply and the system prompt", withoutTools)
+	}
+	if withTools >= withoutTools {
+		t.Errorf("budget with tools is %d, want less than %d without", withTools, withoutTools)
+	}
+	if withTools < contextWindow/2 {
+		t.Errorf("budget is %d, want most of the %d token window", withTools, contextWindow)
+	}
+}
@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+type AnthropicProvider struct {
+	client    anthropic.Client
+	modelName string
+}
+
+func NewAnthropicProvider(client anthropic.Client, modelName string) *AnthropicProvider {
+	return &AnthropicProvider{
+		client:    client,
+		modelName: modelName,
+	}
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, request CompletionRequest) (*anthropic.Message, error) {
+	return p.client.Messages.New(ctx, anthropic.MessageNewParams{
+		MaxTokens: int64(request.MaxTokens),
+		Messages:  request.Messages,
+		Model:     p.modelName,
+		Tools:     request.Tools.GetToolDefinitions(),
+		System: []anthropic.TextBlockParam{
+			{
+				Type: "text",
+				Text: request.System,
+			},
+		},
+	})
+}
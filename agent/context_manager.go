@@ -1,24 +1,47 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/gabe-mason/good-morning/tools"
+	"github.com/pkoukk/tiktoken-go"
 )
 
+const (
+	// recentMessages is the number of trailing messages that are never
+	// compacted, so the model always sees the latest tool results in full.
+	recentMessages = 4
+	// minCompactTokens is the smallest tool result worth summarising.
+	minCompactTokens = 256
+	// trimmedTokens is how much of a tool result is kept when it has to be
+	// trimmed without a summary.
+	trimmedTokens = 200
+	// summarisedMark and trimmedMark label tool results that have already
+	// been compacted.
+	summarisedMark = "[summarised] "
+	trimmedMark    = "\n[trimmed]"
+)
+
+// Summariser condenses an old tool result that no longer fits in the window.
+type Summariser func(ctx context.Context, toolName string, content string) (string, error)
+
 type ContextManager struct {
 	fileLocation   string
 	messages       []anthropic.MessageParam
-	contextWindow  int
+	tokenCounts    []int
 	hasNewMessages bool
+	encoding       *tiktoken.Tiktoken
+	summariser     Summariser
 }
 
-func CreateContextManager(tools tools.ToolCalls, contextWindow int, fileLocation string) *ContextManager {
+func CreateContextManager(tools tools.ToolCalls, fileLocation string) *ContextManager {
 	// Check if file exists, create if it doesn't
 	if _, err := os.Stat(fileLocation); os.IsNotExist(err) {
 		// Create directory if it doesn't exist
@@ -36,17 +59,149 @@ func CreateContextManager(tools tools.ToolCalls, contextWindow int, fileLocation
 		}
 	}
 
+	// The encoding is fetched on first use, fall back to estimating when offline
+	encoding, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		log.Printf("Error loading token encoding, estimating token counts instead: %v", err)
+	}
+
 	// Create context manager
 	cm := &ContextManager{
 		messages:       []anthropic.MessageParam{},
-		contextWindow:  contextWindow,
+		tokenCounts:    []int{},
 		hasNewMessages: false,
 		fileLocation:   fileLocation,
+		encoding:       encoding,
+	}
+
+	// Pick up an earlier run from today
+	if err := cm.load(); err != nil {
+		log.Printf("Error loading context from %s, starting afresh: %v", fileLocation, err)
+		cm.messages = []anthropic.MessageParam{}
+		cm.tokenCounts = []int{}
 	}
 
 	return cm
 }
 
+// savedBlock mirrors the content blocks written by save. The SDK's param
+// unions can be marshaled but not unmarshaled.
+type savedBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	IsError   bool            `json:"is_error"`
+	Content   []savedBlock    `json:"content"`
+}
+
+type savedMessage struct {
+	Role    anthropic.MessageParamRole `json:"role"`
+	Content []savedBlock               `json:"content"`
+}
+
+func (ml *ContextManager) load() error {
+	data, err := os.ReadFile(ml.fileLocation)
+	if err != nil {
+		return fmt.Errorf("error reading messages from file: %v", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var saved []savedMessage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("error unmarshaling messages: %v", err)
+	}
+
+	for _, message := range saved {
+		content := make([]anthropic.ContentBlockParamUnion, 0, len(message.Content))
+		for _, block := range message.Content {
+			switch block.Type {
+			case "text":
+				content = append(content, anthropic.NewTextBlock(block.Text))
+			case "tool_use":
+				content = append(content, anthropic.ContentBlockParamUnion{
+					OfRequestToolUseBlock: &anthropic.ToolUseBlockParam{
+						ID:    block.ID,
+						Name:  block.Name,
+						Input: block.Input,
+					},
+				})
+			case "tool_result":
+				var text string
+				for _, result := range block.Content {
+					text += result.Text
+				}
+				content = append(content, anthropic.NewToolResultBlock(block.ToolUseID, text, block.IsError))
+			default:
+				return fmt.Errorf("unsupported content block type %q", block.Type)
+			}
+		}
+		param := anthropic.MessageParam{Role: message.Role, Content: content}
+		ml.messages = append(ml.messages, param)
+		ml.tokenCounts = append(ml.tokenCounts, ml.countMessageTokens(param))
+	}
+
+	if len(ml.messages) > 0 {
+		ml.hasNewMessages = ml.GetLastMessage().Role == anthropic.MessageParamRoleUser
+	}
+	return nil
+}
+
+// Clear drops the conversation so a new run can start from scratch.
+func (ml *ContextManager) Clear() {
+	ml.messages = []anthropic.MessageParam{}
+	ml.tokenCounts = []int{}
+	ml.hasNewMessages = false
+	ml.save()
+}
+
+// Interrupted reports whether the loaded conversation stopped before the model
+// gave its final answer.
+func (ml *ContextManager) Interrupted() bool {
+	return ml.hasNewMessages || len(ml.PendingToolUses()) > 0
+}
+
+// CompletedToolUses returns the calls to the named tool that already have a
+// result.
+func (ml *ContextManager) CompletedToolUses(name string) []*anthropic.ToolUseBlockParam {
+	messages := ml.messages
+	if len(ml.PendingToolUses()) > 0 {
+		messages = messages[:len(messages)-1]
+	}
+	completed := make([]*anthropic.ToolUseBlockParam, 0)
+	for _, message := range messages {
+		for _, block := range message.Content {
+			if block.OfRequestToolUseBlock != nil && block.OfRequestToolUseBlock.Name == name {
+				completed = append(completed, block.OfRequestToolUseBlock)
+			}
+		}
+	}
+	return completed
+}
+
+// PendingToolUses returns the tool calls the model asked for in the last
+// message that have no results yet.
+func (ml *ContextManager) PendingToolUses() []*anthropic.ToolUseBlockParam {
+	if len(ml.messages) == 0 {
+		return nil
+	}
+	last := ml.GetLastMessage()
+	if last.Role != anthropic.MessageParamRoleAssistant {
+		return nil
+	}
+	pending := make([]*anthropic.ToolUseBlockParam, 0)
+	for _, block := range last.Content {
+		if block.OfRequestToolUseBlock != nil {
+			pending = append(pending, block.OfRequestToolUseBlock)
+		}
+	}
+	return pending
+}
+
 func (ml *ContextManager) save() error {
 	// Convert messages to JSON
 	data, err := json.MarshalIndent(ml.messages, "", "  ")
@@ -91,9 +246,140 @@ func (ml *ContextManager) AppendAssistantMessageAck(message anthropic.MessagePar
 
 func (ml *ContextManager) append(message anthropic.MessageParam) {
 	ml.messages = append(ml.messages, message)
+	ml.tokenCounts = append(ml.tokenCounts, ml.countMessageTokens(message))
 	ml.save()
 }
 
+// SetSummariser sets the side call used to condense old tool results. Without
+// one, old tool results are trimmed instead.
+func (ml *ContextManager) SetSummariser(summariser Summariser) {
+	ml.summariser = summariser
+}
+
+// TokenCount returns the number of tokens in the conversation.
+func (ml *ContextManager) TokenCount() int {
+	total := 0
+	for _, count := range ml.tokenCounts {
+		total += count
+	}
+	return total
+}
+
+// Compact slides the window over the conversation when it exceeds the budget,
+// the tokens of the context window left for the messages. Tool results older than the most recent messages are summarised, or
+// trimmed if summarising fails, oldest first until the conversation fits.
+// Only the content of a tool result is replaced, so every tool_use block keeps
+// its matching tool_result.
+func (ml *ContextManager) Compact(ctx context.Context, budget int) error {
+	if ml.TokenCount() <= budget {
+		return nil
+	}
+
+	toolNames := make(map[string]string)
+	compacted := false
+	for i := 0; i < len(ml.messages)-recentMessages && ml.TokenCount() > budget; i++ {
+		for _, block := range ml.messages[i].Content {
+			if block.OfRequestToolUseBlock != nil {
+				toolNames[block.OfRequestToolUseBlock.ID] = block.OfRequestToolUseBlock.Name
+			}
+		}
+		if ml.messages[i].Role != anthropic.MessageParamRoleUser {
+			continue
+		}
+
+		for _, block := range ml.messages[i].Content {
+			result := block.OfRequestToolResultBlock
+			if result == nil {
+				continue
+			}
+			content := toolResultText(result)
+			if isCompacted(content) || ml.countTokens(content) < minCompactTokens {
+				continue
+			}
+			result.Content = []anthropic.ToolResultBlockParamContentUnion{{
+				OfRequestTextBlock: &anthropic.TextBlockParam{
+					Text: ml.compactToolResult(ctx, toolNames[result.ToolUseID], content),
+				},
+			}}
+			compacted = true
+		}
+		ml.tokenCounts[i] = ml.countMessageTokens(ml.messages[i])
+	}
+
+	if !compacted {
+		return nil
+	}
+	if ml.TokenCount() > budget {
+		log.Printf("Context is %d tokens after compacting, over the budget of %d", ml.TokenCount(), budget)
+	}
+	return ml.save()
+}
+
+func (ml *ContextManager) compactToolResult(ctx context.Context, toolName string, content string) string {
+	if ml.summariser != nil {
+		summary, err := ml.summariser(ctx, toolName, content)
+		if err == nil {
+			return summarisedMark + summary
+		}
+		log.Printf("Error summarising %s result, trimming instead: %v", toolName, err)
+	}
+	return ml.trim(content, trimmedTokens) + trimmedMark
+}
+
+// isCompacted reports whether a tool result has already been summarised or
+// trimmed, so it isn't condensed again.
+func isCompacted(content string) bool {
+	return strings.HasPrefix(content, summarisedMark) || strings.HasSuffix(content, trimmedMark)
+}
+
+func (ml *ContextManager) trim(content string, tokens int) string {
+	if ml.encoding == nil {
+		if len(content) <= tokens*4 {
+			return content
+		}
+		return content[:tokens*4]
+	}
+	encoded := ml.encoding.EncodeOrdinary(content)
+	if len(encoded) <= tokens {
+		return content
+	}
+	return ml.encoding.Decode(encoded[:tokens])
+}
+
+func (ml *ContextManager) countTokens(text string) int {
+	if ml.encoding == nil {
+		// Roughly four characters per token for English text
+		return len(text) / 4
+	}
+	return len(ml.encoding.EncodeOrdinary(text))
+}
+
+func (ml *ContextManager) countMessageTokens(message anthropic.MessageParam) int {
+	total := 0
+	for _, block := range message.Content {
+		switch {
+		case block.OfRequestTextBlock != nil:
+			total += ml.countTokens(block.OfRequestTextBlock.Text)
+		case block.OfRequestToolUseBlock != nil:
+			input, _ := json.Marshal(block.OfRequestToolUseBlock.Input)
+			total += ml.countTokens(block.OfRequestToolUseBlock.Name) + ml.countTokens(string(input))
+		case block.OfRequestToolResultBlock != nil:
+			total += ml.countTokens(toolResultText(block.OfRequestToolResultBlock))
+		}
+	}
+	return total
+}
+
+func toolResultText(result *anthropic.ToolResultBlockParam) string {
+	text := ""
+	for _, content := range result.Content {
+		if content.OfRequestTextBlock != nil {
+			text += content.OfRequestTextBlock.Text
+		}
+	}
+	return text
+}
+
 func (ml *ContextManager) GetMessages() []anthropic.MessageParam {
 	return ml.messages
 }
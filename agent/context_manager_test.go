@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func toolUse(id string) anthropic.MessageParam {
+	return anthropic.MessageParam{
+		Role: anthropic.MessageParamRoleAssistant,
+		Content: []anthropic.ContentBlockParamUnion{{
+			OfRequestToolUseBlock: &anthropic.ToolUseBlockParam{ID: id, Name: "linear", Input: map[string]any{}},
+		}},
+	}
+}
+
+func toolResult(id string, text string) anthropic.MessageParam {
+	return anthropic.MessageParam{
+		Role: anthropic.MessageParamRoleUser,
+		Content: []anthropic.ContentBlockParamUnion{{
+			OfRequestToolResultBlock: &anthropic.ToolResultBlockParam{
+				ToolUseID: id,
+				Content: []anthropic.ToolResultBlockParamContentUnion{{
+					OfRequestTextBlock: &anthropic.TextBlockParam{Text: text},
+				}},
+			},
+		}},
+	}
+}
+
+func TestCompactSkipsCompactedResults(t *testing.T) {
+	long := strings.Repeat("issue ", 1000)
+	summarised := summarisedMark + long
+	trimmed := long + trimmedMark
+
+	ml := &ContextManager{fileLocation: filepath.Join(t.TempDir(), "context.json")}
+	for _, message := range []anthropic.MessageParam{
+		toolUse("fresh"), toolResult("fresh", long),
+		toolUse("summarised"), toolResult("summarised", summarised),
+		toolUse("trimmed"), toolResult("trimmed", trimmed),
+	} {
+		ml.append(message)
+	}
+	for range recentMessages {
+		ml.AppendUserMessage("hello")
+	}
+
+	calls := 0
+	ml.SetSummariser(func(ctx context.Context, toolName string, content string) (string, error) {
+		calls++
+		return "a summary", nil
+	})
+	for range 2 {
+		if err := ml.Compact(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("summarised %d times, want once", calls)
+	}
+	for i, want := range map[int]string{1: summarisedMark + "a summary", 3: summarised, 5: trimmed} {
+		if got := toolResultText(ml.messages[i].Content[0].OfRequestToolResultBlock); got != want {
+			t.Errorf("message %d is %.40q, want %.40q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gabe-mason/good-morning/tools"
+	"github.com/ollama/ollama/api"
+)
+
+// OllamaProvider runs the agent against a local model so nothing leaves the
+// machine. The host is read from OLLAMA_HOST.
+type OllamaProvider struct {
+	client    *api.Client
+	modelName string
+}
+
+func NewOllamaProvider(client *api.Client, modelName string) *OllamaProvider {
+	return &OllamaProvider{
+		client:    client,
+		modelName: modelName,
+	}
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, request CompletionRequest) (*anthropic.Message, error) {
+	toolDefinitions, err := ollamaTools(request.Tools)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := ollamaMessages(request.System, request.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := false
+	var response api.ChatResponse
+	err = p.client.Chat(ctx, &api.ChatRequest{
+		Model:    p.modelName,
+		Messages: messages,
+		Tools:    toolDefinitions,
+		Stream:   &stream,
+		Options: map[string]any{
+			"num_predict": request.MaxTokens,
+		},
+	}, func(chunk api.ChatResponse) error {
+		response = chunk
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error calling ollama: %v", err)
+	}
+
+	return anthropicMessage(response, len(request.Messages))
+}
+
+// ollamaTools maps the tool definitions onto Ollama's function calling format.
+// Ollama only understands a flat list of typed properties with no item types,
+// so the schema of an array's items is written into its description for the
+// model to follow. A property Ollama can't describe is an error rather than
+// being dropped.
+func ollamaTools(toolCalls tools.ToolCalls) (api.Tools, error) {
+	definitions := make(api.Tools, 0, len(toolCalls))
+	for _, toolCall := range toolCalls {
+		definition := toolCall.ToolDefinition()
+		tool := api.Tool{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        definition.Name,
+				Description: definition.Description.Value,
+			},
+		}
+		schema, err := json.Marshal(definition.InputSchema.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling schema for tool %s: %v", definition.Name, err)
+		}
+		var properties map[string]struct {
+			Type        string          `json:"type"`
+			Description string          `json:"description"`
+			Enum        []string        `json:"enum"`
+			Items       json.RawMessage `json:"items"`
+			Properties  json.RawMessage `json:"properties"`
+		}
+		if err := json.Unmarshal(schema, &properties); err != nil {
+			return nil, fmt.Errorf("error converting schema for tool %s: %v", definition.Name, err)
+		}
+		tool.Function.Parameters.Type = "object"
+		tool.Function.Parameters.Properties = make(map[string]struct {
+			Type        string   `json:"type"`
+			Description string   `json:"description"`
+			Enum        []string `json:"enum,omitempty"`
+		}, len(properties))
+		for name, property := range properties {
+			if property.Type == "" || property.Properties != nil {
+				return nil, fmt.Errorf("tool %s property %s can't be described to ollama", definition.Name, name)
+			}
+			description := property.Description
+			if property.Items != nil {
+				description += ". Each item matches the JSON schema " + string(property.Items)
+			}
+			tool.Function.Parameters.Properties[name] = struct {
+				Type        string   `json:"type"`
+				Description string   `json:"description"`
+				Enum        []string `json:"enum,omitempty"`
+			}{property.Type, description, property.Enum}
+		}
+		definitions = append(definitions, tool)
+	}
+	return definitions, nil
+}
+
+func ollamaMessages(system string, messages []anthropic.MessageParam) ([]api.Message, error) {
+	converted := []api.Message{{Role: "system", Content: system}}
+	for _, message := range messages {
+		var text []string
+		var toolCalls []api.ToolCall
+		for _, block := range message.Content {
+			switch {
+			case block.OfRequestTextBlock != nil:
+				text = append(text, block.OfRequestTextBlock.Text)
+			case block.OfRequestToolUseBlock != nil:
+				arguments, err := json.Marshal(block.OfRequestToolUseBlock.Input)
+				if err != nil {
+					return nil, fmt.Errorf("error marshaling tool arguments: %v", err)
+				}
+				var function api.ToolCallFunction
+				function.Name = block.OfRequestToolUseBlock.Name
+				if err := json.Unmarshal(arguments, &function.Arguments); err != nil {
+					return nil, fmt.Errorf("error converting tool arguments: %v", err)
+				}
+				toolCalls = append(toolCalls, api.ToolCall{Function: function})
+			case block.OfRequestToolResultBlock != nil:
+				var result []string
+				for _, content := range block.OfRequestToolResultBlock.Content {
+					if content.OfRequestTextBlock != nil {
+						result = append(result, content.OfRequestTextBlock.Text)
+					}
+				}
+				converted = append(converted, api.Message{
+					Role:    "tool",
+					Content: strings.Join(result, "\n"),
+				})
+			}
+		}
+		if len(text) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+		converted = append(converted, api.Message{
+			Role:      string(message.Role),
+			Content:   strings.Join(text, "\n"),
+			ToolCalls: toolCalls,
+		})
+	}
+	return converted, nil
+}
+
+// anthropicMessage converts Ollama's reply back into an Anthropic message so
+// the rest of the agent does not need to know which provider answered. Ollama
+// does not issue tool call IDs, so they are derived from the conversation
+// position.
+func anthropicMessage(response api.ChatResponse, position int) (*anthropic.Message, error) {
+	content := make([]map[string]any, 0)
+	if response.Message.Content != "" {
+		content = append(content, map[string]any{
+			"type": "text",
+			"text": response.Message.Content,
+		})
+	}
+	for i, toolCall := range response.Message.ToolCalls {
+		content = append(content, map[string]any{
+			"type":  "tool_use",
+			"id":    fmt.Sprintf("ollama_%d_%d", position, i),
+			"name":  toolCall.Function.Name,
+			"input": toolCall.Function.Arguments,
+		})
+	}
+
+	stopReason := anthropic.MessageStopReasonEndTurn
+	if len(response.Message.ToolCalls) > 0 {
+		stopReason = anthropic.MessageStopReasonToolUse
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"type":        "message",
+		"role":        "assistant",
+		"model":       response.Model,
+		"content":     content,
+		"stop_reason": stopReason,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling ollama response: %v", err)
+	}
+	var message anthropic.Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("error converting ollama response: %v", err)
+	}
+	return &message, nil
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gabe-mason/good-morning/tools"
+)
+
+// nestedTool has an object property, which Ollama can't describe.
+type nestedTool struct{}
+
+func (nestedTool) Run(ctx context.Context, arguments json.RawMessage) (string, error) {
+	return "", nil
+}
+
+func (nestedTool) Name() string {
+	return "nested"
+}
+
+func (nestedTool) ToolDefinition() *anthropic.ToolParam {
+	return &anthropic.ToolParam{
+		Name: "nested",
+		InputSchema: anthropic.ToolInputSchemaParam{Properties: map[string]any{
+			"meeting": map[string]any{"type": "object", "properties": map[string]any{"title": map[string]any{"type": "string"}}},
+		}},
+	}
+}
+
+func TestOllamaTools(t *testing.T) {
+	tests := []struct {
+		name            string
+		tool            tools.ToolCall
+		property        string
+		wantType        string
+		wantDescription string
+	}{
+		{
+			name:            "array of objects",
+			tool:            tools.NewMarkdownWriter(nil),
+			property:        "sections",
+			wantType:        "array",
+			wantDescription: `The sections to write to the markdown document. Each item matches the JSON schema {"properties":{"title":{"type":"string","description":"The title of the section e.g. Calendar, without the markdown heading"},"content":{"type":"string","description":"The markdown content of the section, without its heading"},"comments":{"items":{"type":"string"},"type":"array","description":"The comments to write to the section"}},"additionalProperties":false,"type":"object","required":["title","content","comments"]}`,
+		},
+		{
+			name:            "array of strings",
+			tool:            tools.NewLinear("token", 10, time.UTC, time.Hour, nil),
+			property:        "teams",
+			wantType:        "array",
+			wantDescription: `The teams to get issues, cycles or projects from, create_issue uses the first team. Each item matches the JSON schema {"type":"string"}`,
+		},
+		{
+			name:            "plain property",
+			tool:            tools.NewLinear("token", 10, time.UTC, time.Hour, nil),
+			property:        "issue",
+			wantType:        "string",
+			wantDescription: "The identifier of the issue to comment on, move or assign, such as ENG-123",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			definitions, err := ollamaTools(tools.ToolCalls{test.tool})
+			if err != nil {
+				t.Fatal(err)
+			}
+			parameters := definitions[0].Function.Parameters
+			if parameters.Type != "object" {
+				t.Errorf("parameters are a %q, want an object", parameters.Type)
+			}
+			property, ok := parameters.Properties[test.property]
+			if !ok {
+				t.Fatalf("%s is missing from %+v", test.property, parameters.Properties)
+			}
+			if property.Type != test.wantType {
+				t.Errorf("%s is a %q, want %q", test.property, property.Type, test.wantType)
+			}
+			if property.Description != test.wantDescription {
+				t.Errorf("%s is described as:\n%s\nwant:\n%s", test.property, property.Description, test.wantDescription)
+			}
+		})
+	}
+}
+
+func TestOllamaToolsRejectsNestedObjects(t *testing.T) {
+	if _, err := ollamaTools(tools.ToolCalls{nestedTool{}}); err == nil {
+		t.Error("converted an object property, want an error")
+	}
+}
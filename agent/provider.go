@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gabe-mason/good-morning/tools"
+)
+
+// Provider is the LLM backend the agent talks to. The conversation is kept in
+// Anthropic's message format, providers translate to and from it.
+type Provider interface {
+	Complete(ctx context.Context, request CompletionRequest) (*anthropic.Message, error)
+}
+
+type CompletionRequest struct {
+	System    string
+	Messages  []anthropic.MessageParam
+	Tools     tools.ToolCalls
+	MaxTokens int
+}
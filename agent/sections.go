@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/gabe-mason/good-morning/config"
+)
+
+// Section is a part of the daily summary the agent can regenerate on its own.
+type Section struct {
+	Name     string
+	Title    string
+	Heading  string
+	Prompt   string
+	Template string
+}
+
+var (
+	CalendarSection = Section{
+		Name:    "calendar",
+		Title:   "Calendar",
+		Heading: "## Calendar 📅",
+		Prompt:  "Create a section for each meeting I have, include a note of the people in attendance and the topic of the meeting, with some space for notes. Use the start time of each meeting, it is already in my time zone. Group the meetings by calendar when they come from more than one. For the call link use the meeting's video_call, whichever provider it is, with its meeting ID and passcode when it has them, and leave the line out when there is none. Mark tentative meetings with ❓, meetings I organise with 🎤 and meetings I'm optional in with (optional).",
+		Template: `## Calendar 📅
+
+### {emoji representing meeting type} {time} | {meeting title}
+- **Attendees**: {attendees}
+- **Topic**: {meeting topic}
+- **Zoom**: {zoom link}
+
+#### Notes:
+- 
+
+`,
+	}
+
+	ReviewSection = Section{
+		Name:    "review",
+		Title:   "Things I need to review",
+		Heading: "## Things I need to review 👀",
+		Prompt:  "Create a section for each issue I need to review for my team, is is everyone except me, include a note of the title, author, and priority of the issue with a link to the issue, do not redact.",
+		Template: `## Things I need to review 👀
+
+n items need review from my team:
+
+1. [task identifier](link) - title (assigned to)
+
+`,
+	}
+
+	TodoSection = Section{
+		Name:    "todo",
+		Title:   "Things I need to do",
+		Heading: "## Things I need to do ✅",
+		Prompt:  "Create a section for each thing I need to do, include a note of the title, author, and priority of the issue with a link to the issue. My issues come most urgent first, put the overdue, due today and blocked ones under Needs attention in that order, saying why and what blocks them, and mark stale ones with 🕸️.",
+		Template: `## Things I need to do ✅
+
+Active issues assigned to you:
+
+**Needs attention**:
+- (emoji representing urgency) [task identifier](link) - title(overdue, due today or blocked by)
+
+**High Priority**:
+- (emoji representing priority) [task identifier](link) - title(status)
+
+**In Progress**:
+- (emoji representing priority) [task identifier](link) - title(status)
+
+**To Do**:
+- (emoji representing priority) [task identifier](link) - title(status)
+`,
+	}
+
+	SprintHealthSection = Section{
+		Name:    "sprint_health",
+		Title:   "Sprint health",
+		Heading: "## Sprint health 🏃",
+		Prompt:  "Get the cycle and project progress for my teams from Linear. Summarise each team's active cycle in a line with its completed points, progress and days left, then list its at risk issues. List the projects with their progress and target dates, flagging overdue projects and milestones. Keep it short.",
+		Template: `## Sprint health 🏃
+
+**{team} {cycle}**: {completed points}/{scope points} points ({progress}%), {days remaining} days left
+- ⚠️ [task identifier](link) - title ({reason})
+
+**Projects**:
+- [{project}](link) ({progress}%) - target {target date}, next milestone {milestone} ({milestone target date}) {⚠️ if overdue}
+
+`,
+	}
+
+	// WeekAheadSection is only in the briefing on the week ahead day, for
+	// planning the week.
+	WeekAheadSection = Section{
+		Name:    "week_ahead",
+		Title:   "Week ahead",
+		Heading: "## Week ahead 🗓️",
+		Prompt:  "Check my calendar for this_week and summarise each day in a line or two, flagging the heavy days that are mostly meetings.",
+		Template: `## Week ahead 🗓️
+
+- **{day}** ({meeting time} of meetings): {the main meetings} {⚠️ if heavy}
+
+`,
+	}
+
+	SuggestionsSection = Section{
+		Name:     "suggestions",
+		Title:    "Suggestions",
+		Heading:  "## Suggestions 💡",
+		Prompt:   "Suggest how I could make the most of my day. Base them on the focus section of each calendar day, its free blocks, back to back meetings, double bookings and whether I have a block long enough to focus, and quote the times.",
+		Template: summaryFooter,
+	}
+)
+
+// greetingTitle is the title the model uses for the opening of the summary.
+// Its heading includes the user's name so it is not a fixed Section.
+const greetingTitle = "Good Morning"
+
+// SummarySections are the sections of the summary in the order they are
+// rendered, after the greeting.
+var SummarySections = []Section{CalendarSection, ReviewSection, TodoSection, SprintHealthSection, SuggestionsSection}
+
+// OwnedSections are rewritten on every run. Once the summary exists the rest of
+// it, including notes under each meeting, belongs to the user.
+var OwnedSections = []Section{CalendarSection, ReviewSection, TodoSection, SprintHealthSection}
+
+// SummaryHeadings are the headings of the sections in the order the summary
+// renders them.
+func SummaryHeadings(sections []Section) []string {
+	headings := make([]string, 0, len(sections))
+	for _, section := range sections {
+		headings = append(headings, section.Heading)
+	}
+	return headings
+}
+
+// Order is the order of the sections of today's briefing, for placing a
+// section the summary on disk doesn't have yet.
+func Order(cfg *config.Config, now time.Time) []string {
+	return SummaryHeadings(summarySections(cfg.WeekAhead(now)))
+}
+
+// summarySections are the sections of a briefing, with the week ahead after
+// the calendar on the week ahead day.
+func summarySections(weekAhead bool) []Section {
+	if !weekAhead {
+		return SummarySections
+	}
+	sections := make([]Section, 0, len(SummarySections)+1)
+	for _, section := range SummarySections {
+		sections = append(sections, section)
+		if section.Name == CalendarSection.Name {
+			sections = append(sections, WeekAheadSection)
+		}
+	}
+	return sections
+}
+
+const summaryHeader = `Start each file with some interesting ASCII art max 8 x 8 characters.
+{ascii art}
+# Good Morning {name}!
+{tell me a joke}
+{any comments that you have put them here}
+`
+
+const summaryFooter = `## Suggestions 💡
+-- Add suggestions for my day here
+`
+
+func summaryTitles(sections []Section) []string {
+	titles := []string{greetingTitle}
+	for _, section := range sections {
+		titles = append(titles, section.Title)
+	}
+	return titles
+}
+
+func summaryTemplate(sections []Section) string {
+	template := summaryHeader
+	for _, section := range sections {
+		template += section.Template
+	}
+	return template
+}
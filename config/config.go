@@ -4,37 +4,93 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOllama    = "ollama"
+)
+
 type Config struct {
+	Provider        string
 	AnthropicAPIKey string
+	OllamaModel     string
 	GoodMorningRoot string
-	ICSURL          string
+	CalendarSources []CalendarSource
 	GithubToken     string
+	GithubBaseURL   string
 	LinearToken     string
 	LinearTeams     string
+	MaxResults      int
+	StaleAfter      time.Duration
 	MyName          string
+	MyEmail         string
+	Location        *time.Location
+	BriefingTime    string
+	// WeekAheadDay is the day the briefing covers the whole week, empty when off
+	WeekAheadDay    string
+	CalendarRefresh time.Duration
+	LinearRefresh   time.Duration
+
+	// WorkdayStart and WorkdayEnd are the working hours, as time since midnight
+	WorkdayStart time.Duration
+	WorkdayEnd   time.Duration
+	FocusBlock   time.Duration
+
+	NotesInterval time.Duration
+
+	// LinearAllowedActions are the Linear actions that change things without
+	// asking first
+	LinearAllowedActions []string
+
+	WebhookAddr         string
+	LinearWebhookSecret string
+	GithubWebhookSecret string
 }
 
 func LoadConfig() (*Config, error) {
 	cfg := &Config{}
-	cfg.AnthropicAPIKey = os.Getenv("GOOD_MORNING_ANTHROPIC_API_KEY")
-	if cfg.AnthropicAPIKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY is not set")
+	cfg.Provider = os.Getenv("GOOD_MORNING_PROVIDER")
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderAnthropic
+	}
+	switch cfg.Provider {
+	case ProviderAnthropic:
+		cfg.AnthropicAPIKey = os.Getenv("GOOD_MORNING_ANTHROPIC_API_KEY")
+		if cfg.AnthropicAPIKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY is not set")
+		}
+	case ProviderOllama:
+		cfg.OllamaModel = os.Getenv("GOOD_MORNING_OLLAMA_MODEL")
+		if cfg.OllamaModel == "" {
+			return nil, fmt.Errorf("GOOD_MORNING_OLLAMA_MODEL is not set")
+		}
+	default:
+		return nil, fmt.Errorf("GOOD_MORNING_PROVIDER must be one of %s, %s", ProviderAnthropic, ProviderOllama)
 	}
 	cfg.GoodMorningRoot = os.Getenv("GOOD_MORNING_ROOT")
 	if cfg.GoodMorningRoot == "" {
 		return nil, fmt.Errorf("GOOD_MORNING_ROOT is not set")
 	}
-	cfg.ICSURL = os.Getenv("GOOD_MORNING_ICS_URL")
-	if cfg.ICSURL == "" {
+	icsURL := os.Getenv("GOOD_MORNING_ICS_URL")
+	if icsURL == "" {
 		return nil, fmt.Errorf("GOOD_MORNING_ICS_URL is not set")
 	}
+	sources, err := parseCalendarSources(icsURL)
+	if err != nil {
+		return nil, fmt.Errorf("GOOD_MORNING_ICS_URL is invalid: %v", err)
+	}
+	cfg.CalendarSources = sources
 	cfg.GithubToken = os.Getenv("GOOD_MORNING_GITHUB_TOKEN")
 	if cfg.GithubToken == "" {
 		return nil, fmt.Errorf("GOOD_MORNING_GITHUB_TOKEN is not set")
 	}
+	cfg.GithubBaseURL = os.Getenv("GOOD_MORNING_GITHUB_BASE_URL")
 	cfg.LinearToken = os.Getenv("GOOD_MORNING_LINEAR_TOKEN")
 	if cfg.LinearToken == "" {
 		return nil, fmt.Errorf("GOOD_MORNING_LINEAR_TOKEN is not set")
@@ -43,15 +99,223 @@ func LoadConfig() (*Config, error) {
 	if cfg.LinearTeams == "" {
 		return nil, fmt.Errorf("GOOD_MORNING_LINEAR_TEAMS is not set")
 	}
+	cfg.MaxResults = 50
+	if value := os.Getenv("GOOD_MORNING_MAX_RESULTS"); value != "" {
+		maxResults, err := strconv.Atoi(value)
+		if err != nil || maxResults < 1 {
+			return nil, fmt.Errorf("GOOD_MORNING_MAX_RESULTS must be a positive number")
+		}
+		cfg.MaxResults = maxResults
+	}
 	cfg.MyName = os.Getenv("GOOD_MORNING_MY_NAME")
 	if cfg.MyName == "" {
 		return nil, fmt.Errorf("GOOD_MORNING_MY_NAME is not set")
 	}
+	cfg.Location = time.Local
+	if timezone := os.Getenv("GOOD_MORNING_TIMEZONE"); timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("GOOD_MORNING_TIMEZONE must be an IANA time zone such as Europe/London: %v", err)
+		}
+		cfg.Location = location
+	}
+	cfg.BriefingTime = os.Getenv("GOOD_MORNING_BRIEFING_TIME")
+	if cfg.BriefingTime == "" {
+		cfg.BriefingTime = "08:30"
+	}
+	if _, err := time.Parse("15:04", cfg.BriefingTime); err != nil {
+		return nil, fmt.Errorf("GOOD_MORNING_BRIEFING_TIME must be in HH:MM format: %v", err)
+	}
+	cfg.MyEmail = os.Getenv("GOOD_MORNING_MY_EMAIL")
+	cfg.WeekAheadDay = strings.ToLower(os.Getenv("GOOD_MORNING_WEEK_AHEAD_DAY"))
+	switch cfg.WeekAheadDay {
+	case "":
+		cfg.WeekAheadDay = "monday"
+	case "off":
+		cfg.WeekAheadDay = ""
+	case "monday", "tuesday", "wednesday", "thursday", "friday":
+	default:
+		return nil, fmt.Errorf("GOOD_MORNING_WEEK_AHEAD_DAY must be a weekday or off")
+	}
+	cfg.CalendarRefresh, err = durationFromEnv("GOOD_MORNING_CALENDAR_REFRESH", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LinearRefresh, err = durationFromEnv("GOOD_MORNING_LINEAR_REFRESH", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LinearAllowedActions, err = linearAllowedActions(os.Getenv("GOOD_MORNING_LINEAR_ALLOWED_ACTIONS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.StaleAfter, err = durationFromEnv("GOOD_MORNING_STALE_AFTER", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.WorkdayStart, cfg.WorkdayEnd, err = workingHoursFromEnv("GOOD_MORNING_WORKING_HOURS", "09:00-17:30")
+	if err != nil {
+		return nil, err
+	}
+	cfg.FocusBlock, err = durationFromEnv("GOOD_MORNING_FOCUS_BLOCK", 90*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.NotesInterval, err = durationFromEnv("GOOD_MORNING_NOTES_INTERVAL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	// Creating issues from notes is allowing create_issue without asking
+	if os.Getenv("GOOD_MORNING_NOTES_CREATE_ISSUES") == "true" && !slices.Contains(cfg.LinearAllowedActions, "create_issue") {
+		cfg.LinearAllowedActions = append(cfg.LinearAllowedActions, "create_issue")
+	}
+	cfg.WebhookAddr = os.Getenv("GOOD_MORNING_WEBHOOK_ADDR")
+	if cfg.WebhookAddr != "" {
+		cfg.LinearWebhookSecret = os.Getenv("GOOD_MORNING_LINEAR_WEBHOOK_SECRET")
+		if cfg.LinearWebhookSecret == "" {
+			return nil, fmt.Errorf("GOOD_MORNING_LINEAR_WEBHOOK_SECRET is not set")
+		}
+		cfg.GithubWebhookSecret = os.Getenv("GOOD_MORNING_GITHUB_WEBHOOK_SECRET")
+		if cfg.GithubWebhookSecret == "" {
+			return nil, fmt.Errorf("GOOD_MORNING_GITHUB_WEBHOOK_SECRET is not set")
+		}
+	}
 	return cfg, nil
 }
 
+// Teams returns the Linear team keys from LinearTeams.
+func (cfg *Config) Teams() []string {
+	teams := make([]string, 0)
+	for _, team := range strings.Split(cfg.LinearTeams, ",") {
+		if team = strings.TrimSpace(team); team != "" {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+// CalendarSource is a named calendar URL or path from GOOD_MORNING_ICS_URL.
+type CalendarSource struct {
+	Name string
+	URL  string
+}
+
+var calendarSourceName = regexp.MustCompile(`^[\w -]+$`)
+
+// parseCalendarSources reads a comma separated list of calendars, each either
+// a plain URL or path or named as name=url. Unnamed calendars are called
+// "calendar", "calendar-2" and so on.
+func parseCalendarSources(value string) ([]CalendarSource, error) {
+	sources := make([]CalendarSource, 0)
+	names := make(map[string]bool)
+	unnamed := 0
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source := CalendarSource{URL: entry}
+		// A query string also has an =, but never before the scheme or path
+		if name, url, ok := strings.Cut(entry, "="); ok && calendarSourceName.MatchString(name) {
+			source = CalendarSource{Name: strings.TrimSpace(name), URL: strings.TrimSpace(url)}
+		}
+		if source.Name == "" {
+			unnamed++
+			source.Name = "calendar"
+			if unnamed > 1 {
+				source.Name = fmt.Sprintf("calendar-%d", unnamed)
+			}
+		}
+		if source.URL == "" {
+			return nil, fmt.Errorf("calendar %s has no URL", source.Name)
+		}
+		if names[source.Name] {
+			return nil, fmt.Errorf("calendar %s is listed twice", source.Name)
+		}
+		names[source.Name] = true
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// linearAllowedActions parses a comma separated list of the Linear actions
+// that change things, or all of them.
+func linearAllowedActions(value string) ([]string, error) {
+	writeActions := []string{"comment_on_issue", "move_issue", "assign_me", "create_issue"}
+	actions := make([]string, 0)
+	for _, action := range strings.Split(value, ",") {
+		action = strings.TrimSpace(action)
+		switch {
+		case action == "":
+		case action == "all":
+			return writeActions, nil
+		case slices.Contains(writeActions, action):
+			actions = append(actions, action)
+		default:
+			return nil, fmt.Errorf("GOOD_MORNING_LINEAR_ALLOWED_ACTIONS must list actions from %s, or all", strings.Join(writeActions, ", "))
+		}
+	}
+	return actions, nil
+}
+
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 15m: %v", name, err)
+	}
+	return duration, nil
+}
+
+// MyEmails returns the addresses in MyEmail, which may list several.
+func (cfg *Config) MyEmails() []string {
+	emails := make([]string, 0)
+	for _, email := range strings.Split(cfg.MyEmail, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// workingHoursFromEnv reads working hours such as 09:00-17:30.
+func workingHoursFromEnv(name string, fallback string) (time.Duration, time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		value = fallback
+	}
+	startValue, endValue, _ := strings.Cut(value, "-")
+	start, startErr := time.Parse("15:04", strings.TrimSpace(startValue))
+	end, endErr := time.Parse("15:04", strings.TrimSpace(endValue))
+	if startErr != nil || endErr != nil || !end.After(start) {
+		return 0, 0, fmt.Errorf("%s must be a range of times such as 09:00-17:30", name)
+	}
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start.Sub(midnight), end.Sub(midnight), nil
+}
+
+// WeekAhead reports whether the briefing on the day of t covers the week ahead.
+func (cfg *Config) WeekAhead(t time.Time) bool {
+	return cfg.WeekAheadDay != "" && strings.ToLower(t.In(cfg.Location).Weekday().String()) == cfg.WeekAheadDay
+}
+
+// NextBriefing returns the next weekday at the configured briefing time, in
+// the configured time zone, after the given time.
+func (cfg *Config) NextBriefing(after time.Time) time.Time {
+	briefingTime, _ := time.Parse("15:04", cfg.BriefingTime)
+	after = after.In(cfg.Location)
+	next := time.Date(after.Year(), after.Month(), after.Day(), briefingTime.Hour(), briefingTime.Minute(), 0, 0, cfg.Location)
+	for !next.After(after) || next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
 func (cfg *Config) GetContextManagerLocation() string {
-	now := time.Now()
+	now := time.Now().In(cfg.Location)
 	userHome, err := os.UserHomeDir()
 	if err != nil {
 		return filepath.Join(cfg.GoodMorningRoot, fmt.Sprintf("/context/context_manager_%s.json", now.Format("2006-01-02")))
@@ -60,10 +324,38 @@ func (cfg *Config) GetContextManagerLocation() string {
 }
 
 func (cfg *Config) GetSummaryLocation() string {
-	now := time.Now()
+	now := time.Now().In(cfg.Location)
 	userHome, err := os.UserHomeDir()
 	if err != nil {
 		return filepath.Join(cfg.GoodMorningRoot, fmt.Sprintf("%s.md", now.Format("2006-01-02")))
 	}
 	return filepath.Join(userHome, cfg.GoodMorningRoot, fmt.Sprintf("%s.md", now.Format("2006-01-02")))
 }
+
+func (cfg *Config) GetSectionContextLocation(section string) string {
+	now := time.Now().In(cfg.Location)
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(cfg.GoodMorningRoot, fmt.Sprintf("/context/context_manager_%s_%s.json", now.Format("2006-01-02"), section))
+	}
+	return filepath.Join(userHome, cfg.GoodMorningRoot, fmt.Sprintf("/context/context_manager_%s_%s.json", now.Format("2006-01-02"), section))
+}
+
+// GetLastRunLocation is where the start of the last briefing is recorded. It
+// is kept across days, unlike the other state.
+func (cfg *Config) GetLastRunLocation() string {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(cfg.GoodMorningRoot, "/context/last_run")
+	}
+	return filepath.Join(userHome, cfg.GoodMorningRoot, "/context/last_run")
+}
+
+func (cfg *Config) GetNotesStateLocation() string {
+	now := time.Now().In(cfg.Location)
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(cfg.GoodMorningRoot, fmt.Sprintf("/context/notes_%s.json", now.Format("2006-01-02")))
+	}
+	return filepath.Join(userHome, cfg.GoodMorningRoot, fmt.Sprintf("/context/notes_%s.json", now.Format("2006-01-02")))
+}
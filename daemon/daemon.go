@@ -0,0 +1,200 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gabe-mason/good-morning/agent"
+	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/document"
+	"github.com/gabe-mason/good-morning/tools"
+	"github.com/gabe-mason/good-morning/watcher"
+	"github.com/gabe-mason/good-morning/webhook"
+)
+
+const (
+	briefingAttempts = 5
+	briefingBackoff  = time.Minute
+)
+
+// Daemon generates the briefing each weekday and keeps its sections fresh
+// through the day. Alongside it watches the meeting notes for action items and
+// optionally serves webhooks.
+type Daemon struct {
+	cfg      *config.Config
+	provider agent.Provider
+	tools    tools.ToolCalls
+}
+
+func NewDaemon(cfg *config.Config, provider agent.Provider, tools tools.ToolCalls) *Daemon {
+	return &Daemon{
+		cfg:      cfg,
+		provider: provider,
+		tools:    tools,
+	}
+}
+
+// Run blocks until the context is cancelled.
+func (d *Daemon) Run(ctx context.Context) error {
+	if d.missedBriefing(time.Now()) {
+		fmt.Println("Looks like I missed this morning's briefing, catching up.")
+		d.briefing(ctx)
+	}
+
+	next := d.cfg.NextBriefing(time.Now())
+	fmt.Printf("Next briefing at %s.\n", next.Format("Mon 2006-01-02 15:04"))
+	briefingTimer := time.NewTimer(time.Until(next))
+	defer briefingTimer.Stop()
+
+	calendarTick, stopCalendar := tick(d.cfg.CalendarRefresh)
+	defer stopCalendar()
+	linearTick, stopLinear := tick(d.cfg.LinearRefresh)
+	defer stopLinear()
+
+	tool, _ := d.tools.GetTool("linear")
+	linear, _ := tool.(*tools.Linear)
+	tool, _ = d.tools.GetTool("github")
+	github, _ := tool.(*tools.Github)
+
+	// A nil channel never fires when webhooks are not configured
+	var webhookErrs chan error
+	if d.cfg.WebhookAddr != "" {
+		webhookErrs = make(chan error, 1)
+		go func() {
+			webhookErrs <- webhook.NewServer(d.cfg, linear, github).Run(ctx)
+		}()
+	}
+
+	watcherDone := make(chan struct{})
+	if d.cfg.NotesInterval > 0 {
+		go func() {
+			defer close(watcherDone)
+			watcher.NewWatcher(d.cfg, d.provider, linear).Run(ctx)
+		}()
+	} else {
+		close(watcherDone)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			<-watcherDone
+			if webhookErrs != nil {
+				if err := <-webhookErrs; err != nil {
+					log.Printf("Error stopping webhook server: %v", err)
+				}
+			}
+			fmt.Println("Good night!")
+			return nil
+		case err := <-webhookErrs:
+			return fmt.Errorf("webhook server stopped: %v", err)
+		case <-briefingTimer.C:
+			d.briefing(ctx)
+			next = d.cfg.NextBriefing(time.Now())
+			fmt.Printf("Next briefing at %s.\n", next.Format("Mon 2006-01-02 15:04"))
+			briefingTimer.Reset(time.Until(next))
+		case <-calendarTick:
+			d.refresh(ctx, agent.CalendarSection)
+		case <-linearTick:
+			d.refresh(ctx, agent.ReviewSection, agent.TodoSection)
+		}
+	}
+}
+
+// tick returns a nil channel, which never fires, when the interval is zero.
+func tick(interval time.Duration) (<-chan time.Time, func()) {
+	if interval <= 0 {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+func (d *Daemon) missedBriefing(now time.Time) bool {
+	now = now.In(d.cfg.Location)
+	if !isWeekday(now) {
+		return false
+	}
+	// NextBriefing from midnight gives today's briefing time
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, d.cfg.Location)
+	if d.cfg.NextBriefing(midnight).After(now) {
+		return false
+	}
+	_, err := os.Stat(d.cfg.GetSummaryLocation())
+	return os.IsNotExist(err)
+}
+
+// briefing generates the daily summary, retrying with a backoff so a flaky
+// API call does not mean no briefing at all.
+func (d *Daemon) briefing(ctx context.Context) {
+	backoff := briefingBackoff
+	for attempt := 1; attempt <= briefingAttempts; attempt++ {
+		start := time.Now()
+		briefingAgent := agent.NewAgent(d.provider, d.tools, d.cfg)
+		summary, err := briefingAgent.GenerateDailySummary(ctx)
+		if err == nil {
+			if err := document.Update(d.cfg.GetSummaryLocation(), summary, briefingAgent.Headings(), briefingAgent.OwnedHeadings()...); err != nil {
+				log.Printf("Error writing summary: %v", err)
+				return
+			}
+			d.recordRun(start)
+			return
+		}
+		log.Printf("Error generating briefing (attempt %d of %d): %v", attempt, briefingAttempts, err)
+		if attempt == briefingAttempts {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// recordRun records when the briefing started with the GitHub tool, so the
+// next briefing looks for what changed since.
+func (d *Daemon) recordRun(start time.Time) {
+	tool, _ := d.tools.GetTool("github")
+	github, ok := tool.(*tools.Github)
+	if !ok {
+		return
+	}
+	if err := github.RecordRun(start); err != nil {
+		log.Printf("Error recording the briefing: %v", err)
+	}
+}
+
+// refresh regenerates the given sections of today's summary. Nothing happens
+// until the briefing has been written.
+func (d *Daemon) refresh(ctx context.Context, sections ...agent.Section) {
+	if !isWeekday(time.Now().In(d.cfg.Location)) {
+		return
+	}
+	location := d.cfg.GetSummaryLocation()
+	if _, err := os.Stat(location); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading summary: %v", err)
+		}
+		return
+	}
+
+	for _, section := range sections {
+		content, err := agent.NewSectionAgent(d.provider, d.tools, d.cfg, section).RefreshSection(ctx, section)
+		if err != nil {
+			log.Printf("Error refreshing %s section: %v", section.Name, err)
+			continue
+		}
+		if err := document.Update(location, content, agent.Order(d.cfg, time.Now()), section.Heading); err != nil {
+			log.Printf("Error refreshing %s section: %v", section.Name, err)
+		}
+	}
+}
+
+func isWeekday(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
@@ -0,0 +1,95 @@
+package document
+
+import (
+	"strings"
+
+	"github.com/gabe-mason/good-morning/tools"
+)
+
+// Builder assembles the sections written through the markdown_writer tool.
+// Sections are rendered in the order of the headings it was created with, no
+// matter which order the model writes them in. Sections with unknown titles
+// follow in the order they arrived.
+type Builder struct {
+	headings []string
+	sections map[string]tools.Section
+	extra    []string
+	done     chan struct{}
+}
+
+func NewBuilder(headings ...string) *Builder {
+	return &Builder{
+		headings: headings,
+		sections: make(map[string]tools.Section),
+		done:     make(chan struct{}),
+	}
+}
+
+// Consume collects sections until the channel is closed. Run it in its own
+// goroutine.
+func (b *Builder) Consume(sections <-chan tools.Section) {
+	defer close(b.done)
+	for section := range sections {
+		heading := b.heading(section.Title)
+		if _, ok := b.sections[heading]; !ok && !b.known(heading) {
+			b.extra = append(b.extra, heading)
+		}
+		b.sections[heading] = section
+	}
+}
+
+// Render waits for the sections channel to close and returns the document.
+func (b *Builder) Render() string {
+	<-b.done
+	var doc strings.Builder
+	for _, heading := range append(append([]string{}, b.headings...), b.extra...) {
+		section, ok := b.sections[heading]
+		if !ok {
+			continue
+		}
+		doc.WriteString(heading + "\n\n")
+		if content := strings.TrimSpace(section.Content); content != "" {
+			doc.WriteString(content + "\n\n")
+		}
+		for _, comment := range section.Comments {
+			doc.WriteString("> " + comment + "\n")
+		}
+		if len(section.Comments) > 0 {
+			doc.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(doc.String(), "\n") + "\n"
+}
+
+// Empty waits for the sections channel to close and reports whether nothing
+// was written.
+func (b *Builder) Empty() bool {
+	<-b.done
+	return len(b.sections) == 0
+}
+
+// heading finds the heading for a title, first by an exact match and then by
+// prefix so "Good Morning" matches "# Good Morning Alex!".
+func (b *Builder) heading(title string) string {
+	key := headingKey(title)
+	for _, heading := range b.headings {
+		if headingKey(heading) == key {
+			return heading
+		}
+	}
+	for _, heading := range b.headings {
+		if key != "" && strings.HasPrefix(headingKey(heading), key) {
+			return heading
+		}
+	}
+	return "## " + strings.TrimSpace(strings.TrimLeft(title, "# "))
+}
+
+func (b *Builder) known(heading string) bool {
+	for _, known := range b.headings {
+		if known == heading {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,381 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const notesHeading = "#### Notes"
+
+// fileLock serialises read-modify-write cycles on the summary, which can be
+// written by the daemon and webhooks at the same time.
+var fileLock sync.Mutex
+
+// Document is a daily summary split on its level two headings, so sections the
+// agent owns can be replaced without touching anything else in the file.
+type Document struct {
+	preamble []string
+	sections []*section
+}
+
+// section is a level two heading and the lines below it. Level three headings
+// within it, such as meetings, are subsections that can carry notes.
+type section struct {
+	heading string
+	lines   []string
+}
+
+func Parse(markdown string) *Document {
+	doc := &Document{}
+	var current *section
+	for _, line := range strings.Split(markdown, "\n") {
+		if isHeading(line, 2) {
+			current = &section{heading: line}
+			doc.sections = append(doc.sections, current)
+			continue
+		}
+		if current == nil {
+			doc.preamble = append(doc.preamble, line)
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	return doc
+}
+
+func (d *Document) String() string {
+	lines := append([]string{}, d.preamble...)
+	for _, s := range d.sections {
+		lines = append(lines, s.heading)
+		lines = append(lines, s.lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Replace swaps the section matching the heading for the one in updated.
+// Notes written under a subsection are carried over to the subsection with the
+// same title, and notes under subsections that no longer exist are kept at the
+// end of the section so nothing typed by hand is lost. A section missing from
+// the document is inserted at its place in order, the headings as the summary
+// renders them. It reports whether updated has the section, when it doesn't
+// the document is left as it is.
+func (d *Document) Replace(updated *Document, heading string, order []string) bool {
+	replacement := updated.find(heading)
+	if replacement == nil {
+		return false
+	}
+	existing := d.find(heading)
+	if existing == nil {
+		d.insert(replacement, heading, order)
+		return true
+	}
+	existing.lines = mergeNotes(existing.lines, trimTrailingBlank(replacement.lines))
+	existing.heading = replacement.heading
+	return true
+}
+
+// insert adds a section after the nearest section before it in order that the
+// document has, or else before the nearest one after it, so sections the user
+// added stay where they are. Without either it goes at the end.
+func (d *Document) insert(s *section, heading string, order []string) {
+	at := len(d.sections)
+	if i := slices.IndexFunc(order, func(h string) bool { return headingKey(h) == headingKey(heading) }); i >= 0 {
+		found := false
+		for j := i - 1; j >= 0 && !found; j-- {
+			if k := d.index(order[j]); k >= 0 {
+				at, found = k+1, true
+			}
+		}
+		for j := i + 1; j < len(order) && !found; j++ {
+			if k := d.index(order[j]); k >= 0 {
+				at, found = k, true
+			}
+		}
+	}
+	if at < len(d.sections) && (len(s.lines) == 0 || strings.TrimSpace(s.lines[len(s.lines)-1]) != "") {
+		s.lines = append(s.lines, "")
+	}
+	if at > 0 {
+		if before := d.sections[at-1]; len(before.lines) == 0 || strings.TrimSpace(before.lines[len(before.lines)-1]) != "" {
+			before.lines = append(before.lines, "")
+		}
+	}
+	d.sections = slices.Insert(d.sections, at, s)
+}
+
+func (d *Document) find(heading string) *section {
+	if i := d.index(heading); i >= 0 {
+		return d.sections[i]
+	}
+	return nil
+}
+
+func (d *Document) index(heading string) int {
+	key := headingKey(heading)
+	return slices.IndexFunc(d.sections, func(s *section) bool { return headingKey(s.heading) == key })
+}
+
+// Update writes the summary to path. When the file already exists only the
+// given sections are replaced, everything else is left as it is on disk. A
+// section the summary doesn't have keeps its version on disk, it is an error
+// only when the summary has none of them. Sections the file doesn't have yet
+// are inserted at their place in order.
+func Update(path string, summary string, order []string, headings ...string) error {
+	fileLock.Lock()
+	defer fileLock.Unlock()
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return os.WriteFile(path, []byte(summary), 0644)
+	}
+	return patch(path, func(doc *Document) error {
+		updated := Parse(summary)
+		replaced := false
+		for _, heading := range headings {
+			if doc.Replace(updated, heading, order) {
+				replaced = true
+			}
+		}
+		if !replaced && len(headings) > 0 {
+			return fmt.Errorf("none of the sections %q found in update", headings)
+		}
+		return nil
+	})
+}
+
+// Patch applies a change to the summary at path. The file must already exist.
+func Patch(path string, change func(doc *Document) error) error {
+	fileLock.Lock()
+	defer fileLock.Unlock()
+	return patch(path, change)
+}
+
+func patch(path string, change func(doc *Document) error) error {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading summary: %v", err)
+	}
+
+	doc := Parse(string(current))
+	if err := change(doc); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(doc.String()), 0644); err != nil {
+		return fmt.Errorf("error writing summary: %v", err)
+	}
+	return nil
+}
+
+// Upsert replaces the first line in the section that contains key, or adds
+// the line to the end of the section. It reports whether the section exists.
+func (d *Document) Upsert(heading string, key string, line string) bool {
+	s := d.find(heading)
+	if s == nil {
+		return false
+	}
+	for i, existing := range s.lines {
+		if strings.Contains(existing, key) {
+			s.lines[i] = line
+			return true
+		}
+	}
+	end := len(s.lines)
+	for end > 0 && strings.TrimSpace(s.lines[end-1]) == "" {
+		end--
+	}
+	if end == 0 && len(s.lines) > 0 {
+		// Keep the blank line below the heading of an empty section
+		end = 1
+	}
+	s.lines = append(s.lines[:end:end], append([]string{line}, s.lines[end:]...)...)
+	return true
+}
+
+// AddSection appends an empty section if the document has none with the
+// heading.
+func (d *Document) AddSection(heading string) {
+	if d.find(heading) != nil {
+		return
+	}
+	if len(d.sections) > 0 {
+		last := d.sections[len(d.sections)-1]
+		if len(last.lines) == 0 || strings.TrimSpace(last.lines[len(last.lines)-1]) != "" {
+			last.lines = append(last.lines, "")
+		}
+	}
+	d.sections = append(d.sections, &section{heading: heading, lines: []string{"", ""}})
+}
+
+// Notes returns the notes written under each subsection of the section, keyed
+// by subsection heading. Subsections with empty notes are left out.
+func (d *Document) Notes(heading string) map[string]string {
+	notes := make(map[string]string)
+	s := d.find(heading)
+	if s == nil {
+		return notes
+	}
+	_, subsections := splitSubsections(s.lines)
+	for _, sub := range subsections {
+		start, ok := sub.notes()
+		if !ok {
+			continue
+		}
+		notes[sub.heading] = strings.TrimSpace(strings.Join(sub.lines[start+1:], "\n"))
+	}
+	return notes
+}
+
+// Contains reports whether any line in the section contains key.
+func (d *Document) Contains(heading string, key string) bool {
+	s := d.find(heading)
+	if s == nil {
+		return false
+	}
+	for _, line := range s.lines {
+		if strings.Contains(line, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove drops every line in the section that contains key.
+func (d *Document) Remove(heading string, key string) {
+	s := d.find(heading)
+	if s == nil {
+		return
+	}
+	kept := s.lines[:0]
+	for _, line := range s.lines {
+		if !strings.Contains(line, key) {
+			kept = append(kept, line)
+		}
+	}
+	s.lines = kept
+}
+
+type subsection struct {
+	heading string
+	lines   []string
+}
+
+func splitSubsections(lines []string) ([]string, []*subsection) {
+	var intro []string
+	var subsections []*subsection
+	var current *subsection
+	for _, line := range lines {
+		if isHeading(line, 3) {
+			current = &subsection{heading: line}
+			subsections = append(subsections, current)
+			continue
+		}
+		if current == nil {
+			intro = append(intro, line)
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	return intro, subsections
+}
+
+func joinSubsections(intro []string, subsections []*subsection) []string {
+	lines := append([]string{}, intro...)
+	for _, sub := range subsections {
+		lines = append(lines, sub.heading)
+		lines = append(lines, sub.lines...)
+	}
+	return lines
+}
+
+// notes returns the index of the notes heading and whether anything other than
+// the empty template has been written below it.
+func (s *subsection) notes() (int, bool) {
+	for i, line := range s.lines {
+		if !strings.HasPrefix(line, notesHeading) {
+			continue
+		}
+		for _, note := range s.lines[i+1:] {
+			if trimmed := strings.TrimSpace(note); trimmed != "" && trimmed != "-" {
+				return i, true
+			}
+		}
+		return i, false
+	}
+	return -1, false
+}
+
+func mergeNotes(existing []string, replacement []string) []string {
+	_, oldSubsections := splitSubsections(existing)
+	edited := make(map[string]*subsection)
+	for _, sub := range oldSubsections {
+		if _, ok := sub.notes(); ok {
+			edited[subsectionKey(sub.heading)] = sub
+		}
+	}
+	if len(edited) == 0 {
+		return replacement
+	}
+
+	intro, newSubsections := splitSubsections(replacement)
+	for _, sub := range newSubsections {
+		old, ok := edited[subsectionKey(sub.heading)]
+		if !ok {
+			continue
+		}
+		delete(edited, subsectionKey(sub.heading))
+		oldNotes, _ := old.notes()
+		notes := old.lines[oldNotes:]
+		if newNotes, _ := sub.notes(); newNotes >= 0 {
+			sub.lines = append(sub.lines[:newNotes:newNotes], notes...)
+		} else {
+			sub.lines = append(sub.lines, notes...)
+		}
+	}
+
+	// Keep notes for subsections that have gone, in their original order
+	for _, old := range oldSubsections {
+		if _, ok := edited[subsectionKey(old.heading)]; ok {
+			newSubsections = append(newSubsections, old)
+		}
+	}
+	return joinSubsections(intro, newSubsections)
+}
+
+func isHeading(line string, level int) bool {
+	return strings.HasPrefix(line, strings.Repeat("#", level)+" ")
+}
+
+// headingKey normalises a heading so emoji and spacing differences between
+// runs still match, "## Calendar 📅" becomes "calendar".
+func headingKey(heading string) string {
+	var key strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+// subsectionKey matches meetings on their title, so a meeting that moved to a
+// new time keeps its notes.
+func subsectionKey(heading string) string {
+	if i := strings.LastIndex(heading, "|"); i >= 0 {
+		return headingKey(heading[i+1:])
+	}
+	return headingKey(heading)
+}
+
+func trimTrailingBlank(lines []string) []string {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	// Keep one blank line before the next heading
+	return append(lines[:end:end], "")
+}
@@ -0,0 +1,119 @@
+package document
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const onDisk = `# Good Morning Ada!
+
+## Meetings 📅
+
+### 09:30 Standup
+#### Notes
+Ask about the release
+
+## Things I need to do ✅
+
+- Old todo
+
+## Suggestions 💡
+-- Add suggestions for my day here
+
+## Action items
+
+- Mine
+`
+
+// order is how the summary renders its sections, the review section isn't in
+// the file on disk yet.
+var order = []string{"## Meetings 📅", "## Things I need to review 👀", "## Things I need to do ✅", "## Suggestions 💡"}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  string
+		order    []string
+		headings []string
+		wantErr  bool
+		want     string
+	}{
+		{
+			name:     "replaces the sections in the update",
+			summary:  "## Meetings 📅\n\n### 09:30 Standup\n\n## Things I need to do ✅\n\n- New todo\n",
+			order:    order,
+			headings: []string{"## Meetings 📅", "## Things I need to do ✅"},
+			want:     "# Good Morning Ada!\n\n## Meetings 📅\n\n### 09:30 Standup\n\n#### Notes\nAsk about the release\n\n## Things I need to do ✅\n\n- New todo\n\n## Suggestions 💡\n-- Add suggestions for my day here\n\n## Action items\n\n- Mine\n",
+		},
+		{
+			name:     "keeps a section missing from the update",
+			summary:  "## Things I need to do ✅\n\n- New todo\n",
+			order:    order,
+			headings: []string{"## Meetings 📅", "## Things I need to do ✅"},
+			want:     "# Good Morning Ada!\n\n## Meetings 📅\n\n### 09:30 Standup\n#### Notes\nAsk about the release\n\n## Things I need to do ✅\n\n- New todo\n\n## Suggestions 💡\n-- Add suggestions for my day here\n\n## Action items\n\n- Mine\n",
+		},
+		{
+			name:     "fails when nothing matches",
+			summary:  "## Something else\n\n- New todo\n",
+			order:    order,
+			headings: []string{"## Meetings 📅", "## Things I need to do ✅"},
+			wantErr:  true,
+			want:     onDisk,
+		},
+		{
+			name:     "inserts a new section after the one before it",
+			summary:  "## Things I need to review 👀\n\n- PR 1\n\n## Things I need to do ✅\n\n- New todo\n",
+			order:    order,
+			headings: []string{"## Things I need to review 👀", "## Things I need to do ✅"},
+			want:     "# Good Morning Ada!\n\n## Meetings 📅\n\n### 09:30 Standup\n#### Notes\nAsk about the release\n\n## Things I need to review 👀\n\n- PR 1\n\n## Things I need to do ✅\n\n- New todo\n\n## Suggestions 💡\n-- Add suggestions for my day here\n\n## Action items\n\n- Mine\n",
+		},
+		{
+			name:     "inserts a new first section before the one after it",
+			summary:  "## Things I need to review 👀\n\n- PR 1\n",
+			order:    []string{"## Things I need to review 👀", "## Meetings 📅", "## Things I need to do ✅", "## Suggestions 💡"},
+			headings: []string{"## Things I need to review 👀"},
+			want:     "# Good Morning Ada!\n\n## Things I need to review 👀\n\n- PR 1\n\n## Meetings 📅\n\n### 09:30 Standup\n#### Notes\nAsk about the release\n\n## Things I need to do ✅\n\n- Old todo\n\n## Suggestions 💡\n-- Add suggestions for my day here\n\n## Action items\n\n- Mine\n",
+		},
+		{
+			name:     "appends a section with no place in the order",
+			summary:  "## Things I need to review 👀\n\n- PR 1\n",
+			headings: []string{"## Things I need to review 👀"},
+			want:     onDisk + "\n## Things I need to review 👀\n\n- PR 1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "summary.md")
+			if err := os.WriteFile(path, []byte(onDisk), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Update(path, test.summary, test.order, test.headings...)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error is %v, want error %v", err, test.wantErr)
+			}
+			written, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(written) != test.want {
+				t.Errorf("summary is:\n%s\nwant:\n%s", written, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateWritesANewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	if err := Update(path, onDisk, nil, "## Meetings 📅"); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != onDisk {
+		t.Errorf("summary is:\n%s\nwant:\n%s", written, onDisk)
+	}
+}
@@ -5,6 +5,7 @@ go 1.24.2
 require (
 	github.com/ollama/ollama v0.6.4
 	github.com/pkoukk/tiktoken-go v0.1.7
+	github.com/teambition/rrule-go v1.8.2
 )
 
 require (
@@ -3,13 +3,20 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/gabe-mason/good-morning/agent"
 	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/daemon"
+	"github.com/gabe-mason/good-morning/document"
 	"github.com/gabe-mason/good-morning/tools"
+	"github.com/ollama/ollama/api"
 )
 
 func main() {
@@ -19,21 +26,72 @@ func main() {
 		panic(err)
 	}
 
-	client := anthropic.NewClient(option.WithAPIKey(cfg.AnthropicAPIKey))
+	provider, err := newProvider(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	githubTool, err := tools.NewGithub(cfg.GithubToken, cfg.GithubBaseURL, cfg.MaxResults, cfg.Location, cfg.GetLastRunLocation())
+	if err != nil {
+		panic(err)
+	}
+
+	// The daemon runs unattended, so it only makes the changes allowed in config
+	daemonMode := len(os.Args) > 1 && os.Args[1] == "daemon"
+	confirm := tools.NewConfirmationGate(cfg.LinearAllowedActions, !daemonMode && tools.Interactive())
 
-	agent := agent.NewAgent(client, tools.ToolCalls{
-		tools.NewCalendar(cfg.ICSURL),
-		tools.NewGithub(cfg.GithubToken),
-		tools.NewLinear(cfg.LinearToken),
-	}, cfg)
+	calendarSources := make([]tools.CalendarSource, 0, len(cfg.CalendarSources))
+	for _, source := range cfg.CalendarSources {
+		calendarSources = append(calendarSources, tools.CalendarSource{Name: source.Name, URL: source.URL})
+	}
+	toolCalls := tools.ToolCalls{
+		tools.NewCalendar(calendarSources, cfg.Location, tools.WorkingHours{
+			Start:      cfg.WorkdayStart,
+			End:        cfg.WorkdayEnd,
+			FocusBlock: cfg.FocusBlock,
+		}, tools.CalendarUser{
+			Name:   cfg.MyName,
+			Emails: cfg.MyEmails(),
+		}),
+		githubTool,
+		tools.NewLinear(cfg.LinearToken, cfg.MaxResults, cfg.Location, cfg.StaleAfter, confirm),
+	}
+
+	if daemonMode {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := daemon.NewDaemon(cfg, provider, toolCalls).Run(ctx); err != nil {
+			panic(err)
+		}
+		return
+	}
 
-	summary, err := agent.GenerateDailySummary(ctx)
+	start := time.Now()
+	summaryAgent := agent.NewAgent(provider, toolCalls, cfg)
+	summary, err := summaryAgent.GenerateDailySummary(ctx)
 	if err != nil {
 		panic(err)
 	}
 
-	// Write summary to file
-	if err := os.WriteFile(cfg.GetSummaryLocation(), []byte(summary), 0644); err != nil {
+	// Write summary to file, keeping anything written by hand since the last run
+	if err := document.Update(cfg.GetSummaryLocation(), summary, summaryAgent.Headings(), summaryAgent.OwnedHeadings()...); err != nil {
 		panic(fmt.Errorf("failed to write summary: %v", err))
 	}
+	if err := githubTool.RecordRun(start); err != nil {
+		log.Printf("Error recording the run: %v", err)
+	}
+}
+
+func newProvider(cfg *config.Config) (agent.Provider, error) {
+	switch cfg.Provider {
+	case config.ProviderOllama:
+		client, err := api.ClientFromEnvironment()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ollama client: %v", err)
+		}
+		return agent.NewOllamaProvider(client, cfg.OllamaModel), nil
+	default:
+		client := anthropic.NewClient(option.WithAPIKey(cfg.AnthropicAPIKey))
+		return agent.NewAnthropicProvider(client, anthropic.ModelClaude3_5SonnetLatest), nil
+	}
 }
@@ -4,26 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	ics "github.com/arran4/golang-ical"
 )
 
-func NewCalendar(icsURL string) *Calendar {
+// NewCalendar creates the calendar tool over one or more calendars. Days are
+// read in the given location, which is also used for floating times and all
+// day events. Free time is looked for within the working hours, and the user
+// is who declined meetings are hidden for.
+func NewCalendar(sources []CalendarSource, location *time.Location, workingHours WorkingHours, user CalendarUser) *Calendar {
 	return &Calendar{
-		icsURL: icsURL,
+		sources:      sources,
+		location:     location,
+		workingHours: workingHours,
+		user:         user,
 	}
 }
 
 type Calendar struct {
-	icsURL string
+	sources      []CalendarSource
+	location     *time.Location
+	workingHours WorkingHours
+	user         CalendarUser
 }
 
 type CalendarInput struct {
-	Year  int `json:"year" jsonschema_description:"The year to get events for"`
-	Month int `json:"month" jsonschema_description:"The month to get events for"`
-	Day   int `json:"day" jsonschema_description:"The day to get events for"`
+	Year  int `json:"year,omitempty" jsonschema_description:"The year to get events for"`
+	Month int `json:"month,omitempty" jsonschema_description:"The month to get events for"`
+	Day   int `json:"day,omitempty" jsonschema_description:"The day to get events for"`
+	// Start and End, or Range, replace the single day
+	Start string `json:"start,omitempty" jsonschema_description:"The first day of a range of days to get events for, as YYYY-MM-DD, instead of year, month and day"`
+	End   string `json:"end,omitempty" jsonschema_description:"The last day of the range, as YYYY-MM-DD, defaults to start"`
+	Range string `json:"range,omitempty" jsonschema_description:"A named range of days instead of a date: today, tomorrow, this_week, next_week or next_7_days"`
+	// Format is json unless the raw calendar is needed
+	Format string `json:"format,omitempty" jsonschema_description:"The output format, json (the default) for a compact list of events or ics for the raw calendar"`
 }
 
 func (c *Calendar) Run(ctx context.Context, arguments json.RawMessage) (string, error) {
@@ -35,64 +54,134 @@ func (c *Calendar) Run(ctx context.Context, arguments json.RawMessage) (string,
 			Message:  "invalid JSON format",
 		}
 	}
-
-	// Validate the date components
-	if input.Year < 1900 || input.Year > 2100 {
+	if input.Format != "" && input.Format != "json" && input.Format != "ics" {
 		return "", &InvalidToolArgumentsError{
 			ToolName: c.Name(),
-			Message:  "year must be between 1900 and 2100",
+			Message:  "format must be json or ics",
 		}
 	}
-	if input.Month < 1 || input.Month > 12 {
-		return "", &InvalidToolArgumentsError{
-			ToolName: c.Name(),
-			Message:  "month must be between 1 and 12",
-		}
+
+	from, to, err := c.dateRange(input, time.Now())
+	if err != nil {
+		return "", err
 	}
-	if input.Day < 1 || input.Day > 31 {
-		return "", &InvalidToolArgumentsError{
-			ToolName: c.Name(),
-			Message:  "day must be between 1 and 31",
-		}
+	occurrences, unavailable, err := c.occurrences(ctx, from, to)
+	if err != nil {
+		return "", err
+	}
+	days := int(to.Sub(from).Hours()/24 + 0.5)
+	if days == 1 {
+		fmt.Printf("I can see you have %d meetings today.\n", len(occurrences))
+	} else {
+		fmt.Printf("I can see you have %d meetings over %d days.\n", len(occurrences), days)
 	}
 
-	// Parse the ICS data
-	cal, err := ics.ParseCalendarFromUrl(c.icsURL, ctx)
-	if err != nil {
-		return "", fmt.Errorf("error parsing calendar data: %v", err)
+	if input.Format == "ics" {
+		return c.serializeICS(occurrences, unavailable), nil
+	}
+	if days == 1 {
+		return c.serializeJSON(from, occurrences, unavailable)
+	}
+	return c.serializeRangeJSON(from, to, occurrences, unavailable)
+}
+
+// occurrences reads every calendar and returns the merged events between from
+// and to, in start order, with the names of any calendars that couldn't be
+// read. Meetings I declined are left out.
+func (c *Calendar) occurrences(ctx context.Context, from time.Time, to time.Time) ([]occurrence, []string, error) {
+	occurrences := make([]occurrence, 0)
+	unavailable := make([]string, 0)
+	for _, source := range c.sources {
+		cal, err := source.load(ctx)
+		if err != nil {
+			// Carry on with the other calendars, and let the model know this one is missing
+			log.Printf("Error reading the %s calendar: %v", source.Name, err)
+			unavailable = append(unavailable, source.Name)
+			continue
+		}
+		// Expand recurring events into the instances in the range
+		for _, occurrence := range occurrencesBetween(cal.Events(), from, to, c.location) {
+			if c.attendance(occurrence.event).declined() {
+				continue
+			}
+			occurrence.sources = []string{source.Name}
+			occurrences = append(occurrences, occurrence)
+		}
 	}
+	if len(c.sources) > 0 && len(unavailable) == len(c.sources) {
+		return nil, nil, fmt.Errorf("error parsing calendar data: none of the calendars could be read")
+	}
+	occurrences = mergeOccurrences(occurrences)
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].start.Before(occurrences[j].start)
+	})
+	return occurrences, unavailable, nil
+}
 
+// serializeICS writes the events as a calendar, with the times of each
+// instance and X- properties for the local times and calendars.
+func (c *Calendar) serializeICS(occurrences []occurrence, unavailable []string) string {
 	// Create a new calendar for filtered events
 	filteredCal := ics.NewCalendar()
 	filteredCal.SetMethod(ics.MethodRequest)
 	filteredCal.SetProductId("-//Good Morning//Calendar Tool//EN")
 	filteredCal.SetVersion("2.0")
+	for _, name := range unavailable {
+		filteredCal.CalendarProperties = append(filteredCal.CalendarProperties, ics.CalendarProperty{
+			BaseProperty: ics.BaseProperty{IANAToken: "X-UNAVAILABLE-CALENDAR", Value: name},
+		})
+	}
 
-	// Target date for filtering
-	targetDate := time.Date(input.Year, time.Month(input.Month), input.Day, 0, 0, 0, 0, time.UTC)
+	for _, occurrence := range occurrences {
+		// Add a copy of the event to the filtered calendar, with the times of this instance
+		event := copyEvent(occurrence.event)
+		filteredCal.Components = append(filteredCal.Components, event)
+		if occurrence.allDay {
+			event.SetAllDayStartAt(occurrence.start)
+			event.SetAllDayEndAt(occurrence.end)
+		} else {
+			event.SetStartAt(occurrence.start)
+			event.SetEndAt(occurrence.end)
+		}
+		// The start and end in my time zone, so the model doesn't have to convert from UTC
+		event.SetProperty(ics.ComponentProperty("X-LOCAL-START"), c.localTime(occurrence.start, occurrence.allDay))
+		event.SetProperty(ics.ComponentProperty("X-LOCAL-END"), c.localTime(occurrence.end, occurrence.allDay))
+		event.SetProperty(ics.ComponentProperty("X-SOURCE"), strings.Join(occurrence.sources, ","))
+		if occurrence.recurring {
+			event.SetProperty(ics.ComponentPropertyRecurrenceId, occurrence.start.UTC().Format("20060102T150405Z"))
+		}
+	}
+	// Serialize the filtered calendar
+	return filteredCal.Serialize()
+}
 
-	// Filter events for the specified date
-	for _, event := range cal.Events() {
-		startTime, err := event.GetStartAt()
-		if err != nil {
+// copyEvent copies an event with its parameters and nested components, such
+// as VALARM, leaving out the times and recurrence rules. The copy's property
+// parameters can be changed without touching the original.
+func copyEvent(original *ics.VEvent) *ics.VEvent {
+	event := &ics.VEvent{}
+	for _, prop := range original.Properties {
+		switch ics.ComponentProperty(prop.IANAToken) {
+		case ics.ComponentPropertyDtStart, ics.ComponentPropertyDtEnd, ics.ComponentPropertyDuration,
+			ics.ComponentPropertyRrule, ics.ComponentPropertyRdate, ics.ComponentPropertyExdate, ics.ComponentPropertyRecurrenceId:
 			continue
 		}
-
-		// Check if event occurs on the target date
-		if startTime.Year() == targetDate.Year() &&
-			startTime.Month() == targetDate.Month() &&
-			startTime.Day() == targetDate.Day() {
-			// Add the event to the filtered calendar
-			filteredCal.AddEvent(event.Id())
-			// Copy all properties from the original event
-			for _, prop := range event.Properties {
-				filteredCal.Events()[0].AddProperty(ics.ComponentProperty(prop.IANAToken), prop.Value)
-			}
+		parameters := make(map[string][]string, len(prop.ICalParameters))
+		for name, values := range prop.ICalParameters {
+			parameters[name] = append([]string(nil), values...)
 		}
+		prop.ICalParameters = parameters
+		event.Properties = append(event.Properties, prop)
 	}
-	fmt.Printf("I can see you have %d meetings today.\n", len(filteredCal.Events()))
-	// Serialize the filtered calendar
-	return filteredCal.Serialize(), nil
+	event.Components = append(event.Components, original.Components...)
+	return event
+}
+
+func (c *Calendar) localTime(t time.Time, allDay bool) string {
+	if allDay {
+		return t.In(c.location).Format("2006-01-02")
+	}
+	return t.In(c.location).Format(time.RFC3339)
 }
 
 func (c *Calendar) Name() string {
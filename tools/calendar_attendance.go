@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// CalendarUser is who the calendar belongs to, to find them among a
+// meeting's attendees. Emails are matched first, then the name.
+type CalendarUser struct {
+	Name   string
+	Emails []string
+}
+
+// attendance is my part in a meeting.
+type attendance struct {
+	// status is my PARTSTAT, lower case, or empty when I'm not listed
+	status    string
+	organiser bool
+	optional  bool
+}
+
+func (a attendance) declined() bool {
+	return a.status == strings.ToLower(string(ics.ParticipationStatusDeclined))
+}
+
+// attendance finds me in the meeting's organizer and attendees. Organising a
+// meeting I'm not listed in counts as accepting it.
+func (c *Calendar) attendance(event *ics.VEvent) attendance {
+	var mine attendance
+	if organizer := event.GetProperty(ics.ComponentPropertyOrganizer); organizer != nil {
+		mine.organiser = c.isMe(organizer)
+	}
+	for _, attendee := range event.Attendees() {
+		if !c.isMe(&attendee.IANAProperty) {
+			continue
+		}
+		mine.status = strings.ToLower(string(attendee.ParticipationStatus()))
+		mine.optional = optionalAttendee(&attendee.IANAProperty)
+		break
+	}
+	if mine.status == "" && mine.organiser {
+		mine.status = strings.ToLower(string(ics.ParticipationStatusAccepted))
+	}
+	return mine
+}
+
+func (c *Calendar) isMe(property *ics.IANAProperty) bool {
+	email := strings.TrimPrefix(strings.ToLower(property.Value), "mailto:")
+	for _, mine := range c.user.Emails {
+		if strings.EqualFold(email, mine) {
+			return true
+		}
+	}
+	name := firstParameter(property, ics.ParameterCn)
+	return c.user.Name != "" && strings.EqualFold(strings.TrimSpace(name), c.user.Name)
+}
+
+// optionalAttendee reports whether an attendee was invited as optional.
+func optionalAttendee(property *ics.IANAProperty) bool {
+	return strings.EqualFold(firstParameter(property, ics.ParameterRole), string(ics.ParticipationRoleOptParticipant))
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func TestAttendance(t *testing.T) {
+	calendar := NewCalendar(nil, time.UTC, WorkingHours{}, CalendarUser{Name: "Ada Lovelace", Emails: []string{"ada@example.com", "ada@personal.example"}})
+
+	tests := []struct {
+		name         string
+		people       []string
+		want         attendance
+		wantDeclined bool
+	}{
+		{
+			name:   "accepted, matching the email in any case",
+			people: []string{"ATTENDEE;PARTSTAT=ACCEPTED:MAILTO:Ada@Example.com"},
+			want:   attendance{status: "accepted"},
+		},
+		{
+			name:         "declined",
+			people:       []string{"ATTENDEE;PARTSTAT=ACCEPTED:mailto:grace@example.com", "ATTENDEE;PARTSTAT=DECLINED:mailto:ada@example.com"},
+			want:         attendance{status: "declined"},
+			wantDeclined: true,
+		},
+		{
+			name:   "optional and tentative",
+			people: []string{"ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=TENTATIVE:mailto:ada@personal.example"},
+			want:   attendance{status: "tentative", optional: true},
+		},
+		{
+			name:   "matched by name",
+			people: []string{`ATTENDEE;CN="Ada Lovelace";PARTSTAT=NEEDS-ACTION:mailto:a.lovelace@example.org`},
+			want:   attendance{status: "needs-action"},
+		},
+		{
+			name:   "organiser not listed has accepted",
+			people: []string{"ORGANIZER;CN=Ada Lovelace:mailto:ada@example.com", "ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:grace@example.com"},
+			want:   attendance{status: "accepted", organiser: true},
+		},
+		{
+			name:         "organiser who declined",
+			people:       []string{"ORGANIZER:mailto:ada@example.com", "ATTENDEE;PARTSTAT=DECLINED:mailto:ada@example.com"},
+			want:         attendance{status: "declined", organiser: true},
+			wantDeclined: true,
+		},
+		{
+			name:   "not invited",
+			people: []string{"ORGANIZER;CN=Grace Hopper:mailto:grace@example.com", "ATTENDEE;CN=Ada Byron;PARTSTAT=DECLINED:mailto:byron@example.com"},
+			want:   attendance{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cal, err := ics.ParseCalendar(strings.NewReader("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:meeting@example.com\r\n" +
+				strings.Join(test.people, "\r\n") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := calendar.attendance(cal.Events()[0])
+			if got != test.want {
+				t.Errorf("attendance is %+v, want %+v", got, test.want)
+			}
+			if got.declined() != test.wantDeclined {
+				t.Errorf("declined is %v, want %v", got.declined(), test.wantDeclined)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// descriptionExcerpt is how much of an event's description is kept, the rest
+// is mostly dial-in numbers and boilerplate.
+const descriptionExcerpt = 300
+
+// CalendarDay is the calendar tool's compact JSON output.
+type CalendarDay struct {
+	Date   string          `json:"date"`
+	Events []CalendarEvent `json:"events"`
+	// MeetingTime is the time taken up by meetings, such as 4h30m
+	MeetingTime          string    `json:"meeting_time"`
+	Heavy                bool      `json:"heavy,omitempty"`
+	Focus                *DayFocus `json:"focus"`
+	UnavailableCalendars []string  `json:"unavailable_calendars,omitempty"`
+}
+
+// CalendarEvent is a single meeting, with its times in my time zone.
+type CalendarEvent struct {
+	Start       string             `json:"start"`
+	End         string             `json:"end"`
+	AllDay      bool               `json:"all_day,omitempty"`
+	Title       string             `json:"title"`
+	Organizer   *CalendarAttendee  `json:"organizer,omitempty"`
+	Attendees   []CalendarAttendee `json:"attendees,omitempty"`
+	Location    string             `json:"location,omitempty"`
+	Description string             `json:"description,omitempty"`
+	VideoCall   *VideoCall         `json:"video_call,omitempty"`
+	Recurring   bool               `json:"recurring,omitempty"`
+	Calendars   []string           `json:"calendars"`
+	// MyRSVP, Tentative, Organising and Optional are my part in the meeting
+	MyRSVP     string `json:"my_rsvp,omitempty"`
+	Tentative  bool   `json:"tentative,omitempty"`
+	Organising bool   `json:"organising,omitempty"`
+	Optional   bool   `json:"optional,omitempty"`
+}
+
+type CalendarAttendee struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	// RSVP is the attendee's PARTSTAT, such as accepted or needs-action
+	RSVP     string `json:"rsvp,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+func (c *Calendar) serializeJSON(date time.Time, occurrences []occurrence, unavailable []string) (string, error) {
+	data, err := json.Marshal(c.calendarDay(date, occurrences, unavailable))
+	if err != nil {
+		return "", fmt.Errorf("error encoding events: %v", err)
+	}
+	return string(data), nil
+}
+
+func (c *Calendar) calendarDay(date time.Time, occurrences []occurrence, unavailable []string) CalendarDay {
+	busy := meetingTime(occurrences, date, date.AddDate(0, 0, 1))
+	day := CalendarDay{
+		Date:                 date.Format("2006-01-02"),
+		Events:               make([]CalendarEvent, 0, len(occurrences)),
+		MeetingTime:          formatMeetingTime(busy),
+		Heavy:                busy >= heavyMeetingTime,
+		Focus:                c.focus(date, occurrences),
+		UnavailableCalendars: unavailable,
+	}
+	for _, occurrence := range occurrences {
+		day.Events = append(day.Events, c.calendarEvent(occurrence))
+	}
+	return day
+}
+
+// formatMeetingTime formats a duration as hours and minutes, 4h30m rather
+// than 4h30m0s.
+func formatMeetingTime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func (c *Calendar) calendarEvent(occurrence occurrence) CalendarEvent {
+	event := occurrence.event
+	calendarEvent := CalendarEvent{
+		Start:       c.localTime(occurrence.start, occurrence.allDay),
+		End:         c.localTime(occurrence.end, occurrence.allDay),
+		AllDay:      occurrence.allDay,
+		Title:       propertyValue(event, ics.ComponentPropertySummary),
+		Location:    propertyValue(event, ics.ComponentPropertyLocation),
+		Description: excerpt(propertyValue(event, ics.ComponentPropertyDescription), descriptionExcerpt),
+		VideoCall:   videoCall(event),
+		Recurring:   occurrence.recurring,
+		Calendars:   occurrence.sources,
+	}
+	mine := c.attendance(event)
+	calendarEvent.MyRSVP = mine.status
+	calendarEvent.Tentative = mine.status == strings.ToLower(string(ics.ParticipationStatusTentative))
+	calendarEvent.Organising = mine.organiser
+	calendarEvent.Optional = mine.optional
+	if organizer := event.GetProperty(ics.ComponentPropertyOrganizer); organizer != nil {
+		calendarEvent.Organizer = &CalendarAttendee{
+			Name:  firstParameter(organizer, ics.ParameterCn),
+			Email: strings.TrimPrefix(organizer.Value, "mailto:"),
+		}
+	}
+	for _, attendee := range event.Attendees() {
+		calendarEvent.Attendees = append(calendarEvent.Attendees, CalendarAttendee{
+			Name:     firstParameter(&attendee.IANAProperty, ics.ParameterCn),
+			Email:    attendee.Email(),
+			RSVP:     strings.ToLower(string(attendee.ParticipationStatus())),
+			Optional: optionalAttendee(&attendee.IANAProperty),
+		})
+	}
+	return calendarEvent
+}
+
+func propertyValue(event *ics.VEvent, property ics.ComponentProperty) string {
+	if p := event.GetProperty(property); p != nil {
+		return strings.TrimSpace(p.Value)
+	}
+	return ""
+}
+
+func firstParameter(property *ics.IANAProperty, parameter ics.Parameter) string {
+	if values := property.ICalParameters[string(parameter)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// excerpt collapses the whitespace in text and cuts it to at most n runes.
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
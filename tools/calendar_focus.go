@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+const (
+	// minFreeBlock is the shortest gap worth listing as free time.
+	minFreeBlock = 15 * time.Minute
+	// backToBackGap is the longest gap between meetings that still counts as
+	// back to back, there is no time to do anything in it.
+	backToBackGap = 5 * time.Minute
+	// backToBackMeetings is how many back to back meetings make a run worth
+	// flagging.
+	backToBackMeetings = 3
+)
+
+// WorkingHours are the hours free time is looked for in, as time since
+// midnight, and the length of the shortest useful focus block.
+type WorkingHours struct {
+	Start      time.Duration
+	End        time.Duration
+	FocusBlock time.Duration
+}
+
+// DayFocus is the shape of a working day, for suggestions about when to get
+// work done.
+type DayFocus struct {
+	WorkingHours     string       `json:"working_hours"`
+	FreeBlocks       []TimeBlock  `json:"free_blocks"`
+	LongestFreeBlock string       `json:"longest_free_block"`
+	NoFocusBlock     bool         `json:"no_focus_block,omitempty"`
+	BackToBack       []MeetingRun `json:"back_to_back,omitempty"`
+	Overlaps         []MeetingRun `json:"overlaps,omitempty"`
+}
+
+type TimeBlock struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Length string `json:"length"`
+}
+
+// MeetingRun is a stretch of meetings, either back to back or double booked.
+type MeetingRun struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Meetings []string `json:"meetings"`
+}
+
+// focus finds the free blocks, back to back runs and double bookings in the
+// working hours of a day. All day events and events marked as free do not
+// take up time.
+func (c *Calendar) focus(date time.Time, occurrences []occurrence) *DayFocus {
+	workStart := atTime(date, c.workingHours.Start)
+	workEnd := atTime(date, c.workingHours.End)
+
+	meetings := make([]occurrence, 0)
+	for _, occurrence := range occurrences {
+		if occurrence.allDay || transparent(occurrence.event) {
+			continue
+		}
+		if occurrence.start.Before(workEnd) && occurrence.end.After(workStart) {
+			meetings = append(meetings, occurrence)
+		}
+	}
+	sort.SliceStable(meetings, func(i, j int) bool {
+		return meetings[i].start.Before(meetings[j].start)
+	})
+
+	focus := &DayFocus{
+		WorkingHours: c.clock(workStart) + "-" + c.clock(workEnd),
+		FreeBlocks:   make([]TimeBlock, 0),
+		BackToBack:   c.backToBack(meetings),
+		Overlaps:     c.overlapping(meetings),
+	}
+
+	var longest time.Duration
+	free := workStart
+	for _, meeting := range append(meetings, occurrence{start: workEnd, end: workEnd}) {
+		end := meeting.start
+		if end.After(workEnd) {
+			end = workEnd
+		}
+		if length := end.Sub(free); length >= minFreeBlock {
+			focus.FreeBlocks = append(focus.FreeBlocks, TimeBlock{Start: c.clock(free), End: c.clock(end), Length: formatMeetingTime(length)})
+			if length > longest {
+				longest = length
+			}
+		}
+		if meeting.end.After(free) {
+			free = meeting.end
+		}
+	}
+	focus.LongestFreeBlock = formatMeetingTime(longest)
+	focus.NoFocusBlock = longest < c.workingHours.FocusBlock
+	return focus
+}
+
+// backToBack finds the runs of meetings with no real break between them.
+// Meetings must be in start order.
+func (c *Calendar) backToBack(meetings []occurrence) []MeetingRun {
+	runs := make([]MeetingRun, 0)
+	run := make([]occurrence, 0)
+	var runEnd time.Time
+	flush := func() {
+		if len(run) >= backToBackMeetings {
+			runs = append(runs, c.meetingRun(run, run[0].start, runEnd))
+		}
+	}
+	for _, meeting := range meetings {
+		if len(run) > 0 && meeting.start.Sub(runEnd) > backToBackGap {
+			flush()
+			run = run[:0:0]
+		}
+		run = append(run, meeting)
+		if meeting.end.After(runEnd) || len(run) == 1 {
+			runEnd = meeting.end
+		}
+	}
+	flush()
+	return runs
+}
+
+// overlapping finds the meetings that are double booked, grouping meetings
+// that overlap each other. Meetings must be in start order.
+func (c *Calendar) overlapping(meetings []occurrence) []MeetingRun {
+	runs := make([]MeetingRun, 0)
+	for i := 0; i < len(meetings); {
+		group := []occurrence{meetings[i]}
+		end := meetings[i].end
+		j := i + 1
+		for ; j < len(meetings) && meetings[j].start.Before(end); j++ {
+			group = append(group, meetings[j])
+			if meetings[j].end.After(end) {
+				end = meetings[j].end
+			}
+		}
+		if len(group) > 1 {
+			runs = append(runs, c.meetingRun(group, group[0].start, end))
+		}
+		i = j
+	}
+	return runs
+}
+
+func (c *Calendar) meetingRun(meetings []occurrence, start time.Time, end time.Time) MeetingRun {
+	run := MeetingRun{Start: c.clock(start), End: c.clock(end)}
+	for _, meeting := range meetings {
+		run.Meetings = append(run.Meetings, propertyValue(meeting.event, ics.ComponentPropertySummary))
+	}
+	return run
+}
+
+func (c *Calendar) clock(t time.Time) string {
+	return t.In(c.location).Format("15:04")
+}
+
+// transparent reports whether an event is marked as free time.
+func transparent(event *ics.VEvent) bool {
+	return strings.EqualFold(propertyValue(event, ics.ComponentPropertyTransp), string(ics.TransparencyTransparent))
+}
+
+// atTime returns the time of day on the date, by the clock rather than by
+// adding a duration, so days when the clocks change are right.
+func atTime(date time.Time, sinceMidnight time.Duration) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), int(sinceMidnight.Hours()), int(sinceMidnight.Minutes())%60, 0, 0, date.Location())
+}
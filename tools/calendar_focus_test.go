@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFocus(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := NewCalendar(nil, london, WorkingHours{Start: 9 * time.Hour, End: 17 * time.Hour, FocusBlock: 2 * time.Hour}, CalendarUser{})
+	// The clocks go forward on the 30th, the working day is still 09:00-17:00
+	day := time.Date(2025, time.March, 30, 0, 0, 0, 0, london)
+	at := func(hour, minute int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, london)
+	}
+	meeting := func(summary string, start, end time.Time) occurrence {
+		return occurrence{event: testEvent(summary, ""), start: start, end: end}
+	}
+
+	tests := []struct {
+		name        string
+		occurrences []occurrence
+		want        *DayFocus
+	}{
+		{
+			name: "empty day",
+			want: &DayFocus{
+				WorkingHours:     "09:00-17:00",
+				FreeBlocks:       []TimeBlock{{"09:00", "17:00", "8h"}},
+				LongestFreeBlock: "8h",
+				BackToBack:       []MeetingRun{},
+				Overlaps:         []MeetingRun{},
+			},
+		},
+		{
+			name: "back to back, double booked and past the end of the day",
+			occurrences: []occurrence{
+				meeting("Standup", at(9, 0), at(9, 30)),
+				meeting("Planning", at(9, 30), at(10, 0)),
+				meeting("Design review", at(10, 2), at(10, 30)),
+				meeting("Interview", at(11, 0), at(12, 0)),
+				meeting("1:1", at(11, 30), at(12, 30)),
+				{event: testEvent("Lunch", "TRANSPARENT"), start: at(12, 30), end: at(13, 30)},
+				{event: testEvent("Holiday", ""), start: day, end: day.AddDate(0, 0, 1), allDay: true},
+				meeting("Offsite", at(16, 0), at(18, 0)),
+			},
+			want: &DayFocus{
+				WorkingHours:     "09:00-17:00",
+				FreeBlocks:       []TimeBlock{{"10:30", "11:00", "30m"}, {"12:30", "16:00", "3h30m"}},
+				LongestFreeBlock: "3h30m",
+				BackToBack:       []MeetingRun{{"09:00", "10:30", []string{"Standup", "Planning", "Design review"}}},
+				Overlaps:         []MeetingRun{{"11:00", "12:30", []string{"Interview", "1:1"}}},
+			},
+		},
+		{
+			name: "no block long enough to focus",
+			occurrences: []occurrence{
+				meeting("Standup", at(9, 30), at(10, 0)),
+				meeting("Sync", at(10, 10), at(11, 0)),
+				meeting("Lunch and learn", at(12, 30), at(13, 30)),
+				meeting("Retro", at(15, 0), at(16, 0)),
+			},
+			want: &DayFocus{
+				WorkingHours:     "09:00-17:00",
+				FreeBlocks:       []TimeBlock{{"09:00", "09:30", "30m"}, {"11:00", "12:30", "1h30m"}, {"13:30", "15:00", "1h30m"}, {"16:00", "17:00", "1h"}},
+				LongestFreeBlock: "1h30m",
+				NoFocusBlock:     true,
+				BackToBack:       []MeetingRun{},
+				Overlaps:         []MeetingRun{},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := calendar.focus(day, test.occurrences); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("focus is %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxRangeDays keeps range queries to about a month, longer ranges are too
+// much for the model to read.
+const maxRangeDays = 31
+
+// heavyMeetingTime is how much time in meetings makes a day heavy.
+const heavyMeetingTime = 5 * time.Hour
+
+// CalendarRange is the calendar tool's JSON output for more than one day.
+type CalendarRange struct {
+	Start                string        `json:"start"`
+	End                  string        `json:"end"`
+	Days                 []CalendarDay `json:"days"`
+	UnavailableCalendars []string      `json:"unavailable_calendars,omitempty"`
+}
+
+// dateRange returns the start of the first day and the end of the last day
+// asked for, in my time zone. Named ranges are relative to now.
+func (c *Calendar) dateRange(input CalendarInput, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(c.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.location)
+	// Weeks start on Monday
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+
+	switch {
+	case input.Range != "":
+		switch input.Range {
+		case "today":
+			return today, today.AddDate(0, 0, 1), nil
+		case "tomorrow":
+			return today.AddDate(0, 0, 1), today.AddDate(0, 0, 2), nil
+		case "this_week":
+			return monday, monday.AddDate(0, 0, 7), nil
+		case "next_week":
+			return monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 14), nil
+		case "next_7_days":
+			return today, today.AddDate(0, 0, 7), nil
+		}
+		return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+			ToolName: c.Name(),
+			Message:  "range must be one of today, tomorrow, this_week, next_week or next_7_days",
+		}
+
+	case input.Start != "":
+		start, err := time.ParseInLocation("2006-01-02", input.Start, c.location)
+		if err != nil {
+			return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+				ToolName: c.Name(),
+				Message:  "start must be a date in YYYY-MM-DD format",
+			}
+		}
+		end := start
+		if input.End != "" {
+			end, err = time.ParseInLocation("2006-01-02", input.End, c.location)
+			if err != nil {
+				return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+					ToolName: c.Name(),
+					Message:  "end must be a date in YYYY-MM-DD format",
+				}
+			}
+		}
+		if end.Before(start) {
+			return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+				ToolName: c.Name(),
+				Message:  "end must not be before start",
+			}
+		}
+		if end.After(start.AddDate(0, 0, maxRangeDays-1)) {
+			return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+				ToolName: c.Name(),
+				Message:  fmt.Sprintf("ranges can be at most %d days", maxRangeDays),
+			}
+		}
+		return start, end.AddDate(0, 0, 1), nil
+	}
+
+	// Validate the date components
+	if input.Year < 1900 || input.Year > 2100 {
+		return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+			ToolName: c.Name(),
+			Message:  "year must be between 1900 and 2100",
+		}
+	}
+	if input.Month < 1 || input.Month > 12 {
+		return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+			ToolName: c.Name(),
+			Message:  "month must be between 1 and 12",
+		}
+	}
+	if input.Day < 1 || input.Day > 31 {
+		return time.Time{}, time.Time{}, &InvalidToolArgumentsError{
+			ToolName: c.Name(),
+			Message:  "day must be between 1 and 31",
+		}
+	}
+	targetDate := time.Date(input.Year, time.Month(input.Month), input.Day, 0, 0, 0, 0, c.location)
+	return targetDate, targetDate.AddDate(0, 0, 1), nil
+}
+
+// serializeRangeJSON groups the events by day. Events spanning midnight, and
+// all day events lasting several days, are listed on each day they cover.
+func (c *Calendar) serializeRangeJSON(from time.Time, to time.Time, occurrences []occurrence, unavailable []string) (string, error) {
+	calendarRange := CalendarRange{
+		Start:                from.Format("2006-01-02"),
+		End:                  to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:                 make([]CalendarDay, 0),
+		UnavailableCalendars: unavailable,
+	}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		calendarDay := c.calendarDay(day, occurrencesOn(occurrences, day, next), nil)
+		calendarRange.Days = append(calendarRange.Days, calendarDay)
+	}
+	data, err := json.Marshal(calendarRange)
+	if err != nil {
+		return "", fmt.Errorf("error encoding events: %v", err)
+	}
+	return string(data), nil
+}
+
+func occurrencesOn(occurrences []occurrence, from time.Time, to time.Time) []occurrence {
+	on := make([]occurrence, 0)
+	for _, occurrence := range occurrences {
+		if overlaps(occurrence.start, occurrence.end, from, to) {
+			on = append(on, occurrence)
+		}
+	}
+	return on
+}
+
+// meetingTime is the time between from and to taken up by meetings, counting
+// overlapping meetings once. All day events and events marked as free are not
+// meetings.
+func meetingTime(occurrences []occurrence, from time.Time, to time.Time) time.Duration {
+	type interval struct{ start, end time.Time }
+	intervals := make([]interval, 0)
+	for _, occurrence := range occurrences {
+		if occurrence.allDay || transparent(occurrence.event) {
+			continue
+		}
+		start, end := occurrence.start, occurrence.end
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			intervals = append(intervals, interval{start, end})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	var total time.Duration
+	var current interval
+	for i, next := range intervals {
+		if i > 0 && !next.start.After(current.end) {
+			if next.end.After(current.end) {
+				current.end = next.end
+			}
+			continue
+		}
+		total += current.end.Sub(current.start)
+		current = next
+	}
+	return total + current.end.Sub(current.start)
+}
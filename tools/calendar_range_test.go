@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// testEvent is an event with a summary, marked as free time when transp is
+// TRANSPARENT.
+func testEvent(summary string, transp string) *ics.VEvent {
+	event := ics.NewEvent(summary + "@example.com")
+	event.SetSummary(summary)
+	if transp != "" {
+		event.SetProperty(ics.ComponentPropertyTransp, transp)
+	}
+	return event
+}
+
+func TestDateRange(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := NewCalendar(nil, london, WorkingHours{}, CalendarUser{})
+	// Wednesday morning in London
+	now := time.Date(2025, time.January, 8, 1, 0, 0, 0, london)
+
+	tests := []struct {
+		name      string
+		input     CalendarInput
+		wantStart string
+		wantEnd   string
+		wantErr   bool
+	}{
+		{"today", CalendarInput{Range: "today"}, "2025-01-08", "2025-01-09", false},
+		{"tomorrow", CalendarInput{Range: "tomorrow"}, "2025-01-09", "2025-01-10", false},
+		{"this week starts on Monday", CalendarInput{Range: "this_week"}, "2025-01-06", "2025-01-13", false},
+		{"next week", CalendarInput{Range: "next_week"}, "2025-01-13", "2025-01-20", false},
+		{"next 7 days", CalendarInput{Range: "next_7_days"}, "2025-01-08", "2025-01-15", false},
+		{"unknown range", CalendarInput{Range: "fortnight"}, "", "", true},
+		{"single start", CalendarInput{Start: "2025-03-29"}, "2025-03-29", "2025-03-30", false},
+		{"start and end across the clocks changing", CalendarInput{Start: "2025-03-29", End: "2025-03-31"}, "2025-03-29", "2025-04-01", false},
+		{"longest range", CalendarInput{Start: "2025-01-01", End: "2025-01-31"}, "2025-01-01", "2025-02-01", false},
+		{"too long", CalendarInput{Start: "2025-01-01", End: "2025-02-01"}, "", "", true},
+		{"end before start", CalendarInput{Start: "2025-01-08", End: "2025-01-07"}, "", "", true},
+		{"bad start", CalendarInput{Start: "08/01/2025"}, "", "", true},
+		{"bad end", CalendarInput{Start: "2025-01-08", End: "tomorrow"}, "", "", true},
+		{"year, month and day", CalendarInput{Year: 2025, Month: 2, Day: 14}, "2025-02-14", "2025-02-15", false},
+		{"month out of range", CalendarInput{Year: 2025, Month: 13, Day: 1}, "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, err := calendar.dateRange(test.input, now.In(time.UTC))
+			if test.wantErr {
+				var invalid *InvalidToolArgumentsError
+				if !errors.As(err, &invalid) {
+					t.Errorf("error is %v, want invalid arguments", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, got := range []time.Time{start, end} {
+				if got.Location() != london || got.Hour() != 0 || got.Minute() != 0 {
+					t.Errorf("%s is not midnight in London", got)
+				}
+			}
+			if start.Format("2006-01-02") != test.wantStart || end.Format("2006-01-02") != test.wantEnd {
+				t.Errorf("range is %s to %s, want %s to %s", start.Format("2006-01-02"), end.Format("2006-01-02"), test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
+
+func TestMeetingTime(t *testing.T) {
+	day := time.Date(2025, time.January, 8, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	meeting := func(start, end time.Time) occurrence {
+		return occurrence{event: testEvent("meeting", ""), start: start, end: end}
+	}
+
+	tests := []struct {
+		name        string
+		occurrences []occurrence
+		want        time.Duration
+	}{
+		{"no meetings", nil, 0},
+		{"separate meetings", []occurrence{meeting(at(9, 0), at(10, 0)), meeting(at(14, 0), at(14, 30))}, 90 * time.Minute},
+		{"overlapping meetings count once", []occurrence{meeting(at(9, 0), at(10, 0)), meeting(at(9, 30), at(11, 0))}, 2 * time.Hour},
+		{"a meeting inside another", []occurrence{meeting(at(9, 0), at(12, 0)), meeting(at(10, 0), at(10, 30))}, 3 * time.Hour},
+		{"cut at the end of the day", []occurrence{meeting(at(23, 0), at(25, 0))}, time.Hour},
+		{"all day events are not meetings", []occurrence{{event: testEvent("holiday", ""), start: day, end: day.AddDate(0, 0, 1), allDay: true}}, 0},
+		{"free events are not meetings", []occurrence{{event: testEvent("lunch", "TRANSPARENT"), start: at(12, 0), end: at(13, 0)}}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := meetingTime(test.occurrences, day, day.AddDate(0, 0, 1)); got != test.want {
+				t.Errorf("meeting time is %s, want %s", got, test.want)
+			}
+		})
+	}
+}
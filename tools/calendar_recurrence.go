@@ -0,0 +1,248 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/teambition/rrule-go"
+)
+
+// occurrence is a single instance of an event. Recurring events have one per
+// instance, each with its own start and end.
+type occurrence struct {
+	event     *ics.VEvent
+	start     time.Time
+	end       time.Time
+	allDay    bool
+	recurring bool
+	// sources are the names of the calendars the event was found in
+	sources []string
+}
+
+// occurrencesBetween expands the events into the instances that overlap the
+// range. Recurring events are expanded with their RRULE and RDATE, minus their
+// EXDATE. Instances moved or changed with a RECURRENCE-ID replace the instance
+// they override, and cancelled events or instances are dropped. Floating times
+// and all day events are in the given location.
+func occurrencesBetween(events []*ics.VEvent, from time.Time, to time.Time, location *time.Location) []occurrence {
+	// Overrides are keyed by UID and the start of the instance they replace
+	overrides := make(map[string]map[int64]bool)
+	for _, event := range events {
+		recurrenceID := event.GetProperty(ics.ComponentPropertyRecurrenceId)
+		if recurrenceID == nil {
+			continue
+		}
+		originalStart, err := parseICSTime(recurrenceID, location)
+		if err != nil {
+			continue
+		}
+		if overrides[event.Id()] == nil {
+			overrides[event.Id()] = make(map[int64]bool)
+		}
+		overrides[event.Id()][originalStart.Unix()] = true
+	}
+
+	occurrences := make([]occurrence, 0)
+	for _, event := range events {
+		if cancelled(event) {
+			continue
+		}
+		start, allDay, err := eventStart(event, location)
+		if err != nil {
+			continue
+		}
+		duration := eventDuration(event, start, allDay)
+
+		isOverride := event.HasProperty(ics.ComponentPropertyRecurrenceId)
+		if isOverride || !isRecurring(event) {
+			end := start.Add(duration)
+			if overlaps(start, end, from, to) {
+				occurrences = append(occurrences, occurrence{event: event, start: start, end: end, allDay: allDay, recurring: isOverride})
+			}
+			continue
+		}
+
+		starts, err := recurrenceStarts(event, start, from.Add(-duration), to)
+		if err != nil {
+			continue
+		}
+		for _, instanceStart := range starts {
+			if overrides[event.Id()][instanceStart.Unix()] {
+				continue
+			}
+			end := instanceStart.Add(duration)
+			if overlaps(instanceStart, end, from, to) {
+				occurrences = append(occurrences, occurrence{event: event, start: instanceStart, end: end, allDay: allDay, recurring: true})
+			}
+		}
+	}
+	return occurrences
+}
+
+func isRecurring(event *ics.VEvent) bool {
+	return event.HasProperty(ics.ComponentPropertyRrule) || event.HasProperty(ics.ComponentPropertyRdate)
+}
+
+func cancelled(event *ics.VEvent) bool {
+	status := event.GetProperty(ics.ComponentPropertyStatus)
+	return status != nil && strings.EqualFold(status.Value, string(ics.ObjectStatusCancelled))
+}
+
+// overlaps reports whether an event overlaps the range. Events without a
+// duration count when they start within it.
+func overlaps(start time.Time, end time.Time, from time.Time, to time.Time) bool {
+	if !end.After(start) {
+		return !start.Before(from) && start.Before(to)
+	}
+	return start.Before(to) && end.After(from)
+}
+
+func eventStart(event *ics.VEvent, location *time.Location) (time.Time, bool, error) {
+	property := event.GetProperty(ics.ComponentPropertyDtStart)
+	if property == nil {
+		return time.Time{}, false, fmt.Errorf("event has no start")
+	}
+	start, err := parseICSTime(property, location)
+	return start, isDate(property), err
+}
+
+// eventDuration uses DTEND, then DURATION, falling back to a day for all day
+// events and nothing otherwise.
+func eventDuration(event *ics.VEvent, start time.Time, allDay bool) time.Duration {
+	if property := event.GetProperty(ics.ComponentPropertyDtEnd); property != nil {
+		if end, err := parseICSTime(property, start.Location()); err == nil && !end.Before(start) {
+			return end.Sub(start)
+		}
+	}
+	if property := event.GetProperty(ics.ComponentPropertyDuration); property != nil {
+		if duration, err := parseICSDuration(property.Value); err == nil {
+			return duration
+		}
+	}
+	if allDay {
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// recurrenceStarts returns the starts of the instances of a recurring event
+// between from and to.
+func recurrenceStarts(event *ics.VEvent, start time.Time, from time.Time, to time.Time) ([]time.Time, error) {
+	set := &rrule.Set{}
+	set.DTStart(start)
+	for _, property := range event.GetProperties(ics.ComponentPropertyRrule) {
+		option, err := rrule.StrToROptionInLocation(property.Value, start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing RRULE %q: %v", property.Value, err)
+		}
+		option.Dtstart = start
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing RRULE %q: %v", property.Value, err)
+		}
+		set.RRule(rule)
+	}
+	for _, property := range event.GetProperties(ics.ComponentPropertyRdate) {
+		dates, err := parseICSTimeList(property, start.Location())
+		if err != nil {
+			return nil, err
+		}
+		for _, date := range dates {
+			set.RDate(date)
+		}
+	}
+	for _, property := range event.GetProperties(ics.ComponentPropertyExdate) {
+		dates, err := parseICSTimeList(property, start.Location())
+		if err != nil {
+			return nil, err
+		}
+		for _, date := range dates {
+			set.ExDate(date)
+		}
+	}
+	return set.Between(from, to, true), nil
+}
+
+var icsTimeLayouts = []string{"20060102T150405Z", "20060102T150405", "20060102"}
+
+// parseICSTime parses a date or date-time property value. Values with a TZID
+// are in that zone, or the fallback location when it is unknown. UTC values
+// end in Z and anything else is floating, in the fallback location.
+func parseICSTime(property *ics.IANAProperty, fallback *time.Location) (time.Time, error) {
+	dates, err := parseICSTimeList(property, fallback)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return dates[0], nil
+}
+
+// parseICSTimeList parses a property that may hold a comma separated list,
+// such as EXDATE.
+func parseICSTimeList(property *ics.IANAProperty, fallback *time.Location) ([]time.Time, error) {
+	location := fallback
+	if tzid, ok := property.ICalParameters[string(ics.ParameterTzid)]; ok && len(tzid) > 0 {
+		location = tzidLocation(tzid[0], fallback)
+	}
+
+	dates := make([]time.Time, 0)
+	for _, value := range strings.Split(property.Value, ",") {
+		value = strings.TrimSpace(value)
+		var parsed time.Time
+		var err error
+		for _, layout := range icsTimeLayouts {
+			if strings.HasSuffix(layout, "Z") {
+				parsed, err = time.Parse(layout, value)
+			} else {
+				parsed, err = time.ParseInLocation(layout, value, location)
+			}
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing time %q: %v", value, err)
+		}
+		dates = append(dates, parsed)
+	}
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("property %s has no value", property.IANAToken)
+	}
+	return dates, nil
+}
+
+func isDate(property *ics.IANAProperty) bool {
+	if value, ok := property.ICalParameters[string(ics.ParameterValue)]; ok && len(value) > 0 {
+		return strings.EqualFold(value[0], "DATE")
+	}
+	return len(property.Value) == len("20060102")
+}
+
+var icsDuration = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration parses an RFC 5545 duration such as PT1H30M or P1D.
+func parseICSDuration(value string) (time.Duration, error) {
+	matched := icsDuration.FindStringSubmatch(value)
+	if matched == nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if matched[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(matched[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		duration += time.Duration(n) * unit
+	}
+	if matched[1] == "-" {
+		duration = -duration
+	}
+	return duration, nil
+}
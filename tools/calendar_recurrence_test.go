@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func loadFixture(t *testing.T, name string) *ics.Calendar {
+	t.Helper()
+	cal, err := CalendarSource{Name: name, URL: "testdata/" + name}.load(context.Background())
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return cal
+}
+
+func TestOccurrencesBetween(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := loadFixture(t, "recurring.ics")
+	day := func(d int) time.Time { return time.Date(2025, time.January, d, 0, 0, 0, 0, london) }
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want []string
+	}{
+		{"first instance", day(6), day(7), []string{"Standup 09:30"}},
+		{"single event with a duration and a yearly all day event", day(7), day(8), []string{"Birthday 00:00", "Standup 09:30", "Planning 14:00"}},
+		{"EXDATE and a cancelled event", day(8), day(9), nil},
+		{"moved instance", day(9), day(10), []string{"Standup (moved) 11:00"}},
+		{"cancelled instance", day(10), day(11), nil},
+		{"after the exceptions", day(13), day(14), []string{"Standup 09:30"}},
+		{"range", day(6), day(11), []string{"Standup 09:30", "Birthday 00:00", "Standup 09:30", "Planning 14:00", "Standup (moved) 11:00"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			occurrences := occurrencesBetween(cal.Events(), test.from, test.to, london)
+			slices.SortStableFunc(occurrences, func(a, b occurrence) int {
+				return a.start.Compare(b.start)
+			})
+			var got []string
+			for _, occurrence := range occurrences {
+				got = append(got, propertyValue(occurrence.event, ics.ComponentPropertySummary)+" "+occurrence.start.In(london).Format("15:04"))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesBetweenMarksRecurring(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := loadFixture(t, "recurring.ics")
+	from := time.Date(2025, time.January, 7, 0, 0, 0, 0, london)
+	for _, occurrence := range occurrencesBetween(cal.Events(), from, from.AddDate(0, 0, 3), london) {
+		summary := propertyValue(occurrence.event, ics.ComponentPropertySummary)
+		if want := summary != "Planning"; occurrence.recurring != want {
+			t.Errorf("%s: recurring is %v, want %v", summary, occurrence.recurring, want)
+		}
+		if want := summary == "Birthday"; occurrence.allDay != want {
+			t.Errorf("%s: all day is %v, want %v", summary, occurrence.allDay, want)
+		}
+	}
+}
+
+func TestParseICSTime(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		tzid  string
+		value string
+		want  string
+	}{
+		{"IANA zone", "America/New_York", "20250106T093000", "2025-01-06T09:30:00-05:00"},
+		{"IANA zone with a leading slash", "/Asia/Tokyo", "20250106T093000", "2025-01-06T09:30:00+09:00"},
+		{"Windows zone", "Pacific Standard Time", "20250106T093000", "2025-01-06T09:30:00-08:00"},
+		{"Windows zone in summer", "W. Europe Standard Time", "20250706T093000", "2025-07-06T09:30:00+02:00"},
+		{"unknown zone is floating", "Somewhere Standard Time", "20250706T093000", "2025-07-06T09:30:00+01:00"},
+		{"UTC", "", "20250106T093000Z", "2025-01-06T09:30:00Z"},
+		{"floating in winter", "", "20250106T093000", "2025-01-06T09:30:00Z"},
+		{"floating in summer", "", "20250706T093000", "2025-07-06T09:30:00+01:00"},
+		{"date", "", "20250706", "2025-07-06T00:00:00+01:00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			property := &ics.IANAProperty{BaseProperty: ics.BaseProperty{
+				IANAToken:      "DTSTART",
+				ICalParameters: map[string][]string{},
+				Value:          test.value,
+			}}
+			if test.tzid != "" {
+				property.ICalParameters[string(ics.ParameterTzid)] = []string{test.tzid}
+			}
+			got, err := parseICSTime(property, london)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Format(time.RFC3339) != test.want {
+				t.Errorf("got %s, want %s", got.Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
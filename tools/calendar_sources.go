@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// CalendarSource is a named calendar, read from an http(s) or webcal URL or a
+// local .ics file.
+type CalendarSource struct {
+	Name string
+	URL  string
+}
+
+// load fetches and parses the calendar.
+func (s CalendarSource) load(ctx context.Context) (*ics.Calendar, error) {
+	switch {
+	case strings.HasPrefix(s.URL, "webcal://"):
+		return ics.ParseCalendarFromUrl("https://"+strings.TrimPrefix(s.URL, "webcal://"), ctx)
+	case strings.HasPrefix(s.URL, "http://"), strings.HasPrefix(s.URL, "https://"):
+		return ics.ParseCalendarFromUrl(s.URL, ctx)
+	}
+
+	file, err := os.Open(strings.TrimPrefix(s.URL, "file://"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ics.ParseCalendar(file)
+}
+
+// mergeOccurrences drops events that appear in more than one calendar, such
+// as a meeting on both my work and on-call calendars. Events are the same when
+// they share a UID and start, or a start and title. Events without a UID are
+// only matched by title. The first calendar's copy is kept and tagged with
+// every calendar it was found in.
+func mergeOccurrences(occurrences []occurrence) []occurrence {
+	merged := make([]occurrence, 0, len(occurrences))
+	seen := make(map[string]int)
+	for _, occurrence := range occurrences {
+		start := occurrence.start.Unix()
+		keys := make([]string, 0, 2)
+		if uid := occurrence.event.Id(); uid != "" {
+			keys = append(keys, fmt.Sprintf("uid:%s:%d", uid, start))
+		}
+		if summary := occurrence.event.GetProperty(ics.ComponentPropertySummary); summary != nil {
+			keys = append(keys, fmt.Sprintf("title:%s:%d", strings.ToLower(strings.TrimSpace(summary.Value)), start))
+		}
+
+		duplicate := -1
+		for _, key := range keys {
+			if i, ok := seen[key]; ok {
+				duplicate = i
+				break
+			}
+		}
+		if duplicate >= 0 {
+			if !slices.Contains(merged[duplicate].sources, occurrence.sources[0]) {
+				merged[duplicate].sources = append(merged[duplicate].sources, occurrence.sources[0])
+			}
+			continue
+		}
+
+		for _, key := range keys {
+			seen[key] = len(merged)
+		}
+		merged = append(merged, occurrence)
+	}
+	return merged
+}
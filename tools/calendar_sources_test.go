@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func TestMergeOccurrences(t *testing.T) {
+	nine := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+	event := func(uid string, summary string, start time.Time, source string) occurrence {
+		event := ics.NewEvent(uid)
+		event.SetSummary(summary)
+		return occurrence{event: event, start: start, sources: []string{source}}
+	}
+
+	tests := []struct {
+		name        string
+		occurrences []occurrence
+		want        [][]string
+	}{
+		{
+			name:        "same UID and start",
+			occurrences: []occurrence{event("a", "Standup", nine, "work"), event("a", "Daily standup", nine, "on-call")},
+			want:        [][]string{{"work", "on-call"}},
+		},
+		{
+			name:        "same title and start",
+			occurrences: []occurrence{event("a", "Standup", nine, "work"), event("b", " standup ", nine, "on-call")},
+			want:        [][]string{{"work", "on-call"}},
+		},
+		{
+			name:        "same UID at another time",
+			occurrences: []occurrence{event("a", "Standup", nine, "work"), event("a", "Standup", nine.AddDate(0, 0, 1), "on-call")},
+			want:        [][]string{{"work"}, {"on-call"}},
+		},
+		{
+			name:        "no UIDs and different titles",
+			occurrences: []occurrence{event("", "Standup", nine, "work"), event("", "Dentist", nine, "personal")},
+			want:        [][]string{{"work"}, {"personal"}},
+		},
+		{
+			name:        "no UIDs and the same title",
+			occurrences: []occurrence{event("", "Standup", nine, "work"), event("", "Standup", nine, "on-call")},
+			want:        [][]string{{"work", "on-call"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged := mergeOccurrences(test.occurrences)
+			if len(merged) != len(test.want) {
+				t.Fatalf("got %d events, want %d", len(merged), len(test.want))
+			}
+			for i, occurrence := range merged {
+				if !slices.Equal(occurrence.sources, test.want[i]) {
+					t.Errorf("event %d is from %v, want %v", i, occurrence.sources, test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+type wantAttendee struct {
+	email string
+	cn    string
+	rsvp  ics.ParticipationStatus
+}
+
+type wantEvent struct {
+	summary   string
+	location  string
+	attendees []wantAttendee
+	alarms    []string
+}
+
+var meetingsFixture = map[string]wantEvent{
+	"design-review@example.com": {
+		summary:  "Design review",
+		location: "Room 1",
+		attendees: []wantAttendee{
+			{"ada@example.com", "Ada Lovelace", ics.ParticipationStatusAccepted},
+			{"grace@example.com", "Grace Hopper", ics.ParticipationStatusTentative},
+		},
+		alarms: []string{"-PT10M"},
+	},
+	"one-to-one@example.com": {
+		summary:   "1:1 with Linus",
+		location:  "https://meet.google.com/abc-defg-hij",
+		attendees: []wantAttendee{{"linus@example.com", "Linus Torvalds", ics.ParticipationStatusNeedsAction}},
+	},
+	"retro@example.com": {
+		summary:  "Retro",
+		location: "Room 2",
+		attendees: []wantAttendee{
+			{"margaret@example.com", "Margaret Hamilton", ics.ParticipationStatusDeclined},
+			{"alan@example.com", "Alan Turing", ics.ParticipationStatusAccepted},
+		},
+		alarms: []string{"-PT5M", "-PT1M"},
+	},
+}
+
+// checkEvent checks an event has its own properties, attendees and alarms,
+// and none of another event's.
+func checkEvent(t *testing.T, event *ics.VEvent, want wantEvent) {
+	t.Helper()
+	if got := propertyValue(event, ics.ComponentPropertySummary); got != want.summary {
+		t.Errorf("summary is %q, want %q", got, want.summary)
+	}
+	if got := propertyValue(event, ics.ComponentPropertyLocation); got != want.location {
+		t.Errorf("location is %q, want %q", got, want.location)
+	}
+	if got := len(event.GetProperties(ics.ComponentPropertySummary)); got != 1 {
+		t.Errorf("has %d summaries, want 1", got)
+	}
+
+	attendees := event.Attendees()
+	if len(attendees) != len(want.attendees) {
+		t.Fatalf("has %d attendees, want %d", len(attendees), len(want.attendees))
+	}
+	for i, attendee := range attendees {
+		wantAttendee := want.attendees[i]
+		if got := attendee.Email(); got != wantAttendee.email {
+			t.Errorf("attendee %d is %q, want %q", i, got, wantAttendee.email)
+		}
+		if got := firstParameter(&attendee.IANAProperty, ics.ParameterCn); got != wantAttendee.cn {
+			t.Errorf("attendee %d CN is %q, want %q", i, got, wantAttendee.cn)
+		}
+		if got := attendee.ParticipationStatus(); got != wantAttendee.rsvp {
+			t.Errorf("attendee %d PARTSTAT is %q, want %q", i, got, wantAttendee.rsvp)
+		}
+	}
+
+	alarms := event.Alarms()
+	if len(alarms) != len(want.alarms) {
+		t.Fatalf("has %d alarms, want %d", len(alarms), len(want.alarms))
+	}
+	for i, alarm := range alarms {
+		trigger := alarm.GetProperty(ics.ComponentPropertyTrigger)
+		if trigger == nil || trigger.Value != want.alarms[i] {
+			t.Errorf("alarm %d trigger is %v, want %q", i, trigger, want.alarms[i])
+		}
+	}
+}
+
+func TestCopyEvent(t *testing.T) {
+	cal := loadFixture(t, "meetings.ics")
+	for _, original := range cal.Events() {
+		t.Run(original.Id(), func(t *testing.T) {
+			event := copyEvent(original)
+			checkEvent(t, event, meetingsFixture[original.Id()])
+			for _, property := range []ics.ComponentProperty{ics.ComponentPropertyDtStart, ics.ComponentPropertyDtEnd} {
+				if event.HasProperty(property) {
+					t.Errorf("copy has %s, times are set per occurrence", property)
+				}
+			}
+
+			// Changing the copy's parameters leaves the original alone
+			for _, attendee := range event.Attendees() {
+				attendee.ICalParameters[string(ics.ParameterCn)][0] = "changed"
+			}
+			checkEvent(t, original, meetingsFixture[original.Id()])
+		})
+	}
+}
+
+func TestSerializeICS(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar := NewCalendar(nil, london, WorkingHours{}, CalendarUser{})
+	from := time.Date(2025, time.January, 7, 0, 0, 0, 0, london)
+	occurrences := occurrencesBetween(loadFixture(t, "meetings.ics").Events(), from, from.AddDate(0, 0, 1), london)
+	for i := range occurrences {
+		occurrences[i].sources = []string{"work"}
+	}
+
+	serialized, err := ics.ParseCalendar(strings.NewReader(calendar.serializeICS(occurrences, []string{"personal"})))
+	if err != nil {
+		t.Fatalf("parsing the serialized calendar: %v", err)
+	}
+	events := serialized.Events()
+	if len(events) != len(meetingsFixture) {
+		t.Fatalf("got %d events, want %d", len(events), len(meetingsFixture))
+	}
+	for _, event := range events {
+		t.Run(event.Id(), func(t *testing.T) {
+			checkEvent(t, event, meetingsFixture[event.Id()])
+			if got := propertyValue(event, "X-SOURCE"); got != "work" {
+				t.Errorf("X-SOURCE is %q, want work", got)
+			}
+			if !event.HasProperty(ics.ComponentPropertyDtStart) || !event.HasProperty("X-LOCAL-START") {
+				t.Errorf("has no start")
+			}
+		})
+	}
+}
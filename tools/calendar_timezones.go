@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// windowsZones maps the Windows time zone names Outlook and Exchange write as
+// a TZID onto IANA zones, following the CLDR windowsZones table.
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"UTC-11":                          "Etc/GMT+11",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"Pacific Standard Time (Mexico)":  "America/Tijuana",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time":           "America/New_York",
+	"US Eastern Standard Time":        "America/Indiana/Indianapolis",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Venezuela Standard Time":         "America/Caracas",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Kolkata",
+	"Nepal Standard Time":             "Asia/Kathmandu",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+}
+
+// unknownTZIDs are the TZIDs already logged, so a calendar full of them is
+// only reported once.
+var unknownTZIDs sync.Map
+
+// tzidLocation is the zone of a TZID, either an IANA name, which some
+// calendars write with a leading slash, or a Windows name. A TZID that is
+// neither is logged and read in the fallback location.
+func tzidLocation(tzid string, fallback *time.Location) *time.Location {
+	name := strings.TrimPrefix(strings.Trim(tzid, `"`), "/")
+	if windows, ok := windowsZones[name]; ok {
+		name = windows
+	}
+	if location, err := time.LoadLocation(name); err == nil {
+		return location
+	}
+	if _, logged := unknownTZIDs.LoadOrStore(tzid, true); !logged {
+		log.Printf("Unknown calendar time zone %q, reading its times in %s", tzid, fallback)
+	}
+	return fallback
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// VideoCall is the video conference link of a meeting, with its meeting ID
+// and passcode when the invite has them.
+type VideoCall struct {
+	Provider  string `json:"provider"`
+	URL       string `json:"url"`
+	MeetingID string `json:"meeting_id,omitempty"`
+	Passcode  string `json:"passcode,omitempty"`
+}
+
+type videoProvider struct {
+	name string
+	// link matches the join URL, with the meeting ID in its first group when
+	// the URL has one
+	link *regexp.Regexp
+}
+
+var videoProviders = []videoProvider{
+	{"zoom", regexp.MustCompile(`https://(?:[\w-]+\.)?zoom(?:gov)?\.us/(?:(?:j|w|s)/(\d+)|my/[\w.-]+)(?:\?[^\s<>"'\\]*)?`)},
+	{"google_meet", regexp.MustCompile(`https://meet\.google\.com/([a-z]{3}-[a-z]{4}-[a-z]{3})`)},
+	{"teams", regexp.MustCompile(`https://teams\.(?:microsoft|live)\.com/(?:l/meetup-join|meet)/[^\s<>"'\\]+()`)},
+	{"webex", regexp.MustCompile(`https://[\w-]+\.webex\.com/[^\s<>"'\\]+()`)},
+}
+
+// videoCallFields are the properties invites put conference details in, in the
+// order they are trusted. Descriptions are last as they often link to other
+// meetings too.
+var videoCallFields = []ics.ComponentProperty{
+	"CONFERENCE",
+	"X-GOOGLE-CONFERENCE",
+	"X-MICROSOFT-SKYPETEAMSMEETINGURL",
+	ics.ComponentPropertyLocation,
+	ics.ComponentPropertyUrl,
+	ics.ComponentPropertyDescription,
+	"X-ALT-DESC",
+}
+
+var (
+	meetingIDPattern = regexp.MustCompile(`(?i)(?:meeting id|meeting number|access code)\s*:?\s*(\d[\d ]{6,}\d)`)
+	passcodePattern  = regexp.MustCompile(`(?i)(?:passcode|password|meeting password)\s*:\s*([^\s<>"]+)`)
+)
+
+// videoCall finds the video conference link of a meeting in any of the fields
+// invites put them in. The meeting ID comes from the link when it has one,
+// otherwise from the invite text, as does the passcode.
+func videoCall(event *ics.VEvent) *VideoCall {
+	var call *VideoCall
+	text := make([]string, 0)
+	for _, field := range videoCallFields {
+		for _, property := range event.GetProperties(field) {
+			value := strings.ReplaceAll(property.Value, "&amp;", "&")
+			text = append(text, value)
+			if call != nil {
+				continue
+			}
+			for _, provider := range videoProviders {
+				if matched := provider.link.FindStringSubmatch(value); matched != nil {
+					call = &VideoCall{Provider: provider.name, URL: matched[0], MeetingID: matched[1]}
+					break
+				}
+			}
+		}
+	}
+	if call == nil {
+		return nil
+	}
+
+	invite := strings.Join(text, "\n")
+	if call.MeetingID == "" {
+		if matched := meetingIDPattern.FindStringSubmatch(invite); matched != nil {
+			call.MeetingID = strings.Join(strings.Fields(matched[1]), "")
+		}
+	}
+	if matched := passcodePattern.FindStringSubmatch(invite); matched != nil {
+		call.Passcode = matched[1]
+	}
+	return call
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func TestVideoCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[ics.ComponentProperty]string
+		want       *VideoCall
+	}{
+		{
+			name: "zoom link with the passcode in the description",
+			properties: map[ics.ComponentProperty]string{
+				ics.ComponentPropertyLocation:    "https://us02web.zoom.us/j/81234567890?pwd=abc123",
+				ics.ComponentPropertyDescription: "Join Zoom Meeting\nMeeting ID: 812 3456 7890\nPasscode: 424242",
+			},
+			want: &VideoCall{Provider: "zoom", URL: "https://us02web.zoom.us/j/81234567890?pwd=abc123", MeetingID: "81234567890", Passcode: "424242"},
+		},
+		{
+			name: "zoom personal room takes the ID from the invite",
+			properties: map[ics.ComponentProperty]string{
+				ics.ComponentPropertyDescription: "https://zoom.us/my/ada.lovelace\nMeeting ID: 555 123 4567",
+			},
+			want: &VideoCall{Provider: "zoom", URL: "https://zoom.us/my/ada.lovelace", MeetingID: "5551234567"},
+		},
+		{
+			name: "google meet conference before the description",
+			properties: map[ics.ComponentProperty]string{
+				"X-GOOGLE-CONFERENCE":            "https://meet.google.com/abc-defg-hij",
+				ics.ComponentPropertyDescription: "Notes from last time: https://zoom.us/j/111222333",
+			},
+			want: &VideoCall{Provider: "google_meet", URL: "https://meet.google.com/abc-defg-hij", MeetingID: "abc-defg-hij"},
+		},
+		{
+			name: "location before the description",
+			properties: map[ics.ComponentProperty]string{
+				ics.ComponentPropertyLocation:    "https://meet.google.com/xyz-abcd-efg",
+				ics.ComponentPropertyDescription: "https://zoom.us/j/111222333",
+			},
+			want: &VideoCall{Provider: "google_meet", URL: "https://meet.google.com/xyz-abcd-efg", MeetingID: "xyz-abcd-efg"},
+		},
+		{
+			name: "teams link with escaped ampersands",
+			properties: map[ics.ComponentProperty]string{
+				ics.ComponentPropertyDescription: "Join on your computer\n<https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=x&amp;tenant=y>\nMeeting ID: 123 456 789 012\nPasscode: Xy7Zq",
+			},
+			want: &VideoCall{Provider: "teams", URL: "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=x&tenant=y", MeetingID: "123456789012", Passcode: "Xy7Zq"},
+		},
+		{
+			name: "webex link with an access code",
+			properties: map[ics.ComponentProperty]string{
+				ics.ComponentPropertyUrl:         "https://acme.webex.com/acme/j.php?MTID=m123",
+				ics.ComponentPropertyDescription: "Access code: 2345 678 901\nMeeting password: s3cret",
+			},
+			want: &VideoCall{Provider: "webex", URL: "https://acme.webex.com/acme/j.php?MTID=m123", MeetingID: "2345678901", Passcode: "s3cret"},
+		},
+		{
+			name: "no video link",
+			properties: map[ics.ComponentProperty]string{
+				ics.ComponentPropertyLocation:    "Room 1",
+				ics.ComponentPropertyDescription: "Passcode: 1234, see https://example.com/agenda",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := ics.NewEvent("call@example.com")
+			for property, value := range test.properties {
+				event.SetProperty(property, value)
+			}
+			if got := videoCall(event); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("call is %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
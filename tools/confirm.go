@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ConfirmationGate decides whether the agent may make a change. Actions in
+// the allowlist always may, otherwise the user is asked at the terminal when
+// there is one, and the change is refused when there isn't.
+type ConfirmationGate struct {
+	allowed     []string
+	interactive bool
+	in          *bufio.Reader
+	out         io.Writer
+	mu          sync.Mutex
+}
+
+// NewConfirmationGate creates a gate that asks on stdin and stdout when
+// interactive.
+func NewConfirmationGate(allowed []string, interactive bool) *ConfirmationGate {
+	return &ConfirmationGate{
+		allowed:     allowed,
+		interactive: interactive,
+		in:          bufio.NewReader(os.Stdin),
+		out:         os.Stdout,
+	}
+}
+
+// Interactive reports whether stdin is a terminal someone can answer at.
+func Interactive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Confirm reports whether the action may make the change, which describes it
+// for the user.
+func (g *ConfirmationGate) Confirm(action string, change string) bool {
+	if g == nil {
+		return false
+	}
+	if slices.Contains(g.allowed, action) {
+		return true
+	}
+	if !g.interactive {
+		return false
+	}
+
+	// Ask one question at a time
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(g.out, "The agent wants to %s. Allow it? [y/N] ", change)
+	answer, err := g.in.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
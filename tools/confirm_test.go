@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestGate(allowed []string, interactive bool, answers string) (*ConfirmationGate, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &ConfirmationGate{
+		allowed:     allowed,
+		interactive: interactive,
+		in:          bufio.NewReader(strings.NewReader(answers)),
+		out:         out,
+	}, out
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		interactive bool
+		answers     string
+		want        bool
+		wantPrompt  bool
+	}{
+		{"allowed without asking", []string{"comment_on_issue"}, true, "", true, false},
+		{"allowed when not interactive", []string{"comment_on_issue"}, false, "", true, false},
+		{"y", nil, true, "y\n", true, true},
+		{"yes", []string{"move_issue"}, true, " Yes \n", true, true},
+		{"n", nil, true, "n\n", false, true},
+		{"empty answer", nil, true, "\n", false, true},
+		{"anything else", nil, true, "sure\n", false, true},
+		{"EOF", nil, true, "", false, true},
+		{"answer without a newline", nil, true, "y", false, true},
+		{"not interactive", nil, false, "y\n", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gate, out := newTestGate(test.allowed, test.interactive, test.answers)
+			if got := gate.Confirm("comment_on_issue", "comment on ENG-1"); got != test.want {
+				t.Errorf("Confirm is %v, want %v", got, test.want)
+			}
+			prompt := "The agent wants to comment on ENG-1. Allow it? [y/N] "
+			if got := out.String(); test.wantPrompt != (got == prompt) {
+				t.Errorf("prompt is %q, want prompt %v", got, test.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestConfirmNilGate(t *testing.T) {
+	var gate *ConfirmationGate
+	if gate.Confirm("comment_on_issue", "comment on ENG-1") {
+		t.Error("a nil gate confirmed the change")
+	}
+}
+
+func TestConfirmAsksEachTime(t *testing.T) {
+	gate, _ := newTestGate(nil, true, "y\nn\n")
+	if !gate.Confirm("move_issue", "move ENG-1") {
+		t.Error("first change was not confirmed")
+	}
+	if gate.Confirm("move_issue", "move ENG-1") {
+		t.Error("second change was confirmed")
+	}
+}
+
+const foundIssue = `{"data":{"issue":{"id":"issue-1","identifier":"ENG-1","title":"Fix the build","team":{"states":{"nodes":[{"id":"state-1","name":"In Progress"}]}}}}}`
+
+func TestLinearWritesAreGated(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		answers    string
+		want       string
+		wantUpdate bool
+	}{
+		{"allowed", []string{"move_issue"}, "", "ENG-1", true},
+		{"confirmed", nil, "y\n", "ENG-1", true},
+		{"refused", nil, "n\n", notConfirmed, false},
+		{"no answer", nil, "", notConfirmed, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operations := fakeLinearAPI(t, map[string][]string{
+				"query Issue":          {foundIssue},
+				"mutation UpdateIssue": {`{"data":{"issueUpdate":{"success":true,"issue":{"identifier":"ENG-1","title":"Fix the build","state":{"name":"In Progress"}}}}}`},
+			})
+			gate, _ := newTestGate(test.allowed, true, test.answers)
+			linear := NewLinear("token", 10, time.UTC, time.Hour, gate)
+
+			got, err := linear.moveIssue(context.Background(), "ENG-1", "in progress")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(got, test.want) {
+				t.Errorf("result is %s, want %s", got, test.want)
+			}
+			updated := strings.Contains(strings.Join(*operations, ","), "mutation UpdateIssue")
+			if updated != test.wantUpdate {
+				t.Errorf("updated is %v, want %v", updated, test.wantUpdate)
+			}
+		})
+	}
+}
+
+func TestLookupIssueErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		wantInvalid bool
+		wantErr     string
+	}{
+		{"not found", `{"data":{"issue":null},"errors":[{"message":"Entity not found: Issue"}]}`, true, "issue ENG-1 not found"},
+		{"no issue", `{"data":{"issue":null}}`, true, "issue ENG-1 not found"},
+		{"rate limited", `{"data":{"issue":null},"errors":[{"message":"Rate limit exceeded"}]}`, false, "Rate limit exceeded"},
+		{"error with an issue", `{"data":{"issue":{"id":"issue-1","identifier":"ENG-1"}},"errors":[{"message":"Field not found"}]}`, false, "Field not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeLinearAPI(t, map[string][]string{"query Issue": {test.response}})
+			linear := NewLinear("token", 10, time.UTC, time.Hour, nil)
+
+			_, err := linear.lookupIssue(context.Background(), "ENG-1")
+			if err == nil {
+				t.Fatal("no error")
+			}
+			var invalid *InvalidToolArgumentsError
+			if errors.As(err, &invalid) != test.wantInvalid {
+				t.Errorf("error is %T, want invalid arguments %v", err, test.wantInvalid)
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error is %q, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateIssueIsGated(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		wantCreate bool
+	}{
+		{"allowed", []string{"create_issue"}, true},
+		{"not allowed", []string{"comment_on_issue"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := fakeLinearAPI(t, map[string][]string{
+				"query TeamID":         {`{"data":{"teams":{"nodes":[{"id":"team-1","name":"Engineering"}]}}}`},
+				"mutation CreateIssue": {`{"data":{"issueCreate":{"success":true,"issue":{"identifier":"ENG-2","title":"Send the notes"}}}}`},
+			})
+			// The daemon can't be asked, as when the notes watcher creates issues
+			gate, _ := newTestGate(test.allowed, false, "")
+			linear := NewLinear("token", 10, time.UTC, time.Hour, gate)
+
+			got, err := linear.Run(context.Background(), []byte(`{"action":"create_issue","teams":["ENG"],"title":"Send the notes"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			created := strings.Contains(strings.Join(*requests, ","), "mutation CreateIssue")
+			if created != test.wantCreate {
+				t.Errorf("created is %v, want %v", created, test.wantCreate)
+			}
+			if !test.wantCreate && got != notConfirmed {
+				t.Errorf("result is %s, want %s", got, notConfirmed)
+			}
+		})
+	}
+}
@@ -4,24 +4,80 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/google/go-github/v70/github"
 )
 
+// maxPerPage is the largest page GitHub returns.
+const maxPerPage = 100
+
+// maxPullRequests is how many pull requests are looked at in detail, each one
+// takes a few API calls.
+const maxPullRequests = 30
+
+// maxConcurrentRequests is how many pull requests are looked at in detail at
+// once, to stay clear of GitHub's secondary rate limits.
+const maxConcurrentRequests = 5
+
 type Github struct {
-	token string
+	client     *github.Client
+	maxResults int
+	location   *time.Location
+	// lastRunFile holds when the last briefing started, which is where
+	// notifications and updates are looked from by default
+	lastRunFile string
+
+	// user is who the token belongs to, looked up once
+	user *github.User
+	mu   sync.Mutex
 }
 
-func NewGithub(token string) *Github {
-	return &Github{
-		token: token,
+// NewGithub creates the GitHub tool. The base URL is only needed for GitHub
+// Enterprise Server, such as https://github.example.com, and is api.github.com
+// when empty. Lists stop after maxResults. Dates are read in location.
+func NewGithub(token string, baseURL string, maxResults int, location *time.Location, lastRunFile string) (*Github, error) {
+	client := github.NewClient(nil).WithAuthToken(token)
+	if baseURL != "" {
+		var err error
+		client, err = client.WithEnterpriseURLs(baseURL, baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub base URL: %v", err)
+		}
 	}
+	return &Github{
+		client:      client,
+		maxResults:  maxResults,
+		location:    location,
+		lastRunFile: lastRunFile,
+	}, nil
 }
 
 type GithubInput struct {
-	Action string `json:"action" jsonschema_description:"The action to perform (list_my_prs, list_review_requests)"`
+	Action string `json:"action" jsonschema_description:"The action to perform (list_my_prs, list_review_requests, list_assigned_issues, list_notifications, list_my_prs_needing_attention, list_approved_prs_updated)"`
+	Since  string `json:"since,omitempty" jsonschema_description:"For list_notifications and list_approved_prs_updated, the time to look from as YYYY-MM-DD or RFC 3339, defaults to when the last briefing started"`
+}
+
+// PullRequestSummary is the part of a pull request worth reading in a
+// briefing.
+type PullRequestSummary struct {
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Author string `json:"author"`
+	Draft  bool   `json:"draft,omitempty"`
+	// CIStatus is success, failure, pending or none
+	CIStatus string `json:"ci_status"`
+	// ReviewDecision is approved, changes_requested or review_required
+	ReviewDecision string `json:"review_decision"`
+	// Mergeable is GitHub's mergeable state, such as clean, dirty or blocked
+	Mergeable    string `json:"mergeable"`
+	Age          string `json:"age"`
+	LastActivity string `json:"last_activity"`
 }
 
 func (g *Github) Run(ctx context.Context, arguments json.RawMessage) (string, error) {
@@ -33,60 +89,270 @@ func (g *Github) Run(ctx context.Context, arguments json.RawMessage) (string, er
 		}
 	}
 
+	since := g.lastRun(time.Now())
+	if input.Since != "" {
+		var err error
+		if since, err = parseSince(input.Since, g.location); err != nil {
+			return "", &InvalidToolArgumentsError{
+				ToolName: g.Name(),
+				Message:  "since must be a date in YYYY-MM-DD format or an RFC 3339 time",
+			}
+		}
+	}
+
 	switch input.Action {
 	case "list_my_prs":
 		return g.listMyPRs(ctx)
 	case "list_review_requests":
 		return g.listReviewRequests(ctx)
+	case "list_assigned_issues":
+		return g.listAssignedIssues(ctx)
+	case "list_notifications":
+		return g.listNotifications(ctx, since)
+	case "list_my_prs_needing_attention":
+		return g.listMyPRsNeedingAttention(ctx)
+	case "list_approved_prs_updated":
+		return g.listApprovedPRsUpdated(ctx, since)
 	default:
 		return "", &InvalidToolArgumentsError{
 			ToolName: g.Name(),
-			Message:  "invalid action, supported actions are: list_my_prs, list_review_requests",
+			Message:  "invalid action, supported actions are: list_my_prs, list_review_requests, list_assigned_issues, list_notifications, list_my_prs_needing_attention, list_approved_prs_updated",
+		}
+	}
+}
+
+// me returns the user the token belongs to. It is cached once found,
+// failures are tried again next time.
+func (g *Github) me(ctx context.Context) (*github.User, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.user != nil {
+		return g.user, nil
+	}
+	user, _, err := g.client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the GitHub user: %v", err)
+	}
+	g.user = user
+	return user, nil
+}
+
+// Login returns the login of the user the token belongs to.
+func (g *Github) Login(ctx context.Context) (string, error) {
+	user, err := g.me(ctx)
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
+// search follows the pages of search results up to the limit, and reports
+// whether there were more.
+func (g *Github) search(ctx context.Context, query string) ([]*github.Issue, bool, error) {
+	opts := &github.SearchOptions{
+		Sort:        "updated",
+		ListOptions: github.ListOptions{PerPage: min(g.maxResults, maxPerPage)},
+	}
+	issues := make([]*github.Issue, 0)
+	for {
+		result, resp, err := g.client.Search.Issues(ctx, query, opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to search GitHub: %v", err)
+		}
+		issues = append(issues, result.Issues...)
+		if len(issues) >= g.maxResults {
+			return issues[:g.maxResults], len(issues) > g.maxResults || resp.NextPage != 0, nil
+		}
+		if resp.NextPage == 0 {
+			// GitHub gives up on searches that take too long
+			return issues, result.GetIncompleteResults(), nil
 		}
+		opts.Page = resp.NextPage
 	}
 }
 
-func (g *Github) searchGitHub(ctx context.Context, query string) (string, error) {
-	escapedQuery := url.QueryEscape(query)
-	req, err := http.NewRequestWithContext(ctx, "GET",
-		fmt.Sprintf("https://api.github.com/search/issues?q=%s&advanced_search=true", escapedQuery), nil)
+// searchPullRequests finds the pull requests matching the query and
+// summarises each of them. Only the first maxPullRequests are looked at in
+// detail, the rest are left as unknown.
+func (g *Github) searchPullRequests(ctx context.Context, query string) ([]PullRequestSummary, bool, error) {
+	issues, truncated, err := g.search(ctx, query)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return nil, false, err
+	}
+
+	summaries := make([]PullRequestSummary, len(issues))
+	for i, issue := range issues {
+		summaries[i] = pullRequestSummary(issue)
+	}
+	forEachLimited(min(len(issues), maxPullRequests), func(i int) {
+		g.addDetails(ctx, &summaries[i])
+	})
+	return summaries, truncated, nil
+}
+
+// forEachLimited calls fn for 0 to n-1, running at most maxConcurrentRequests
+// at a time, and waits for them all.
+func forEachLimited(n int, fn func(i int)) {
+	semaphore := make(chan struct{}, maxConcurrentRequests)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// pullRequestSummary summarises a search result, leaving the details search
+// results don't have as unknown.
+func pullRequestSummary(issue *github.Issue) PullRequestSummary {
+	owner, repo := repoFromURL(issue.GetRepositoryURL())
+	return PullRequestSummary{
+		Repo:           owner + "/" + repo,
+		Number:         issue.GetNumber(),
+		Title:          issue.GetTitle(),
+		URL:            issue.GetHTMLURL(),
+		Author:         issue.GetUser().GetLogin(),
+		Draft:          issue.GetDraft(),
+		CIStatus:       "unknown",
+		ReviewDecision: "unknown",
+		Mergeable:      "unknown",
+		Age:            formatAge(time.Since(issue.GetCreatedAt().Time)),
+		LastActivity:   formatAge(time.Since(issue.GetUpdatedAt().Time)) + " ago",
 	}
+}
 
-	req.Header.Set("Authorization", "Bearer "+g.token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+// addDetails looks up the details search results leave out. Details that
+// can't be fetched are left as unknown rather than failing the search.
+func (g *Github) addDetails(ctx context.Context, summary *PullRequestSummary) {
+	owner, repo, _ := strings.Cut(summary.Repo, "/")
+	pr, _, err := g.client.PullRequests.Get(ctx, owner, repo, summary.Number)
+	if err != nil {
+		return
+	}
+	summary.Draft = pr.GetDraft()
+	if state := pr.GetMergeableState(); state != "" {
+		summary.Mergeable = state
+	}
+	if status, err := g.ciStatus(ctx, owner, repo, pr.GetHead().GetSHA()); err == nil {
+		summary.CIStatus = status
+	}
+	if decision, err := g.reviewDecision(ctx, owner, repo, pr.GetNumber()); err == nil {
+		summary.ReviewDecision = decision
+	}
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// ciStatus combines the commit statuses and check runs of a commit. Any
+// failure fails it, then anything still running makes it pending.
+func (g *Github) ciStatus(ctx context.Context, owner string, repo string, sha string) (string, error) {
+	combined, _, err := g.client.Repositories.GetCombinedStatus(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return "", err
+	}
+	checks, _, err := g.client.Checks.ListCheckRunsForRef(ctx, owner, repo, sha, &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to execute request: %v", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	failed, pending, total := false, false, combined.GetTotalCount()
+	switch combined.GetState() {
+	case "failure", "error":
+		failed = true
+	case "pending":
+		pending = total > 0
+	}
+	for _, run := range checks.CheckRuns {
+		total++
+		if run.GetStatus() != "completed" {
+			pending = true
+			continue
+		}
+		switch run.GetConclusion() {
+		case "failure", "timed_out", "cancelled", "action_required", "startup_failure":
+			failed = true
+		}
 	}
 
-	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
+	switch {
+	case failed:
+		return "failure", nil
+	case pending:
+		return "pending", nil
+	case total == 0:
+		return "none", nil
 	}
+	return "success", nil
+}
 
-	jsonResponse, err := json.MarshalIndent(result, "", "  ")
+// reviewDecision works out what GitHub shows as the review decision from each
+// reviewer's latest review.
+func (g *Github) reviewDecision(ctx context.Context, owner string, repo string, number int) (string, error) {
+	reviews, _, err := g.client.PullRequests.ListReviews(ctx, owner, repo, number, &github.ListOptions{PerPage: 100})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal response: %v", err)
+		return "", err
+	}
+	latest := make(map[string]string)
+	for _, review := range reviews {
+		// Comments don't change a reviewer's approval
+		if state := review.GetState(); state == "APPROVED" || state == "CHANGES_REQUESTED" || state == "DISMISSED" {
+			latest[review.GetUser().GetLogin()] = state
+		}
+	}
+
+	approved := false
+	for _, state := range latest {
+		if state == "CHANGES_REQUESTED" {
+			return "changes_requested", nil
+		}
+		approved = approved || state == "APPROVED"
+	}
+	if approved {
+		return "approved", nil
+	}
+	return "review_required", nil
+}
+
+// repoFromURL splits the owner and name out of a repository API URL, such as
+// https://api.github.com/repos/owner/name.
+func repoFromURL(repositoryURL string) (string, string) {
+	parts := strings.Split(strings.TrimSuffix(repositoryURL, "/"), "/")
+	if len(parts) < 2 {
+		return "", ""
 	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
 
-	return string(jsonResponse), nil
+// formatAge formats a duration in its largest whole unit, such as 3d or 5h.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
 }
 
 func (g *Github) listMyPRs(ctx context.Context) (string, error) {
-	return g.searchGitHub(ctx, "is:pull-request is:open author:@me")
+	summaries, truncated, err := g.searchPullRequests(ctx, "is:pull-request is:open author:@me")
+	if err != nil {
+		return "", err
+	}
+	return marshalResults(summaries, truncated, g.maxResults)
 }
 
 func (g *Github) listReviewRequests(ctx context.Context) (string, error) {
-	return g.searchGitHub(ctx, "is:pull-request is:open review-requested:@me")
+	summaries, truncated, err := g.searchPullRequests(ctx, "is:pull-request is:open review-requested:@me")
+	if err != nil {
+		return "", err
+	}
+	return marshalResults(summaries, truncated, g.maxResults)
 }
 
 func (g *Github) Name() string {
@@ -0,0 +1,243 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+)
+
+// IssueSummary is the part of an issue worth reading in a briefing.
+type IssueSummary struct {
+	Repo         string   `json:"repo"`
+	Number       int      `json:"number"`
+	Title        string   `json:"title"`
+	URL          string   `json:"url"`
+	Author       string   `json:"author"`
+	Labels       []string `json:"labels,omitempty"`
+	Age          string   `json:"age"`
+	LastActivity string   `json:"last_activity"`
+}
+
+// NotificationSummary is an unread notification, such as a mention.
+type NotificationSummary struct {
+	Repo string `json:"repo"`
+	// Type is what the notification is about, such as PullRequest or Issue
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	// Reason is why I was notified, such as mention, review_requested or assign
+	Reason  string `json:"reason"`
+	URL     string `json:"url,omitempty"`
+	Updated string `json:"updated"`
+}
+
+// ApprovedPullRequest is a pull request that changed after I approved it.
+type ApprovedPullRequest struct {
+	PullRequestSummary
+	Approved string `json:"approved"`
+}
+
+func (g *Github) listAssignedIssues(ctx context.Context) (string, error) {
+	issues, truncated, err := g.search(ctx, "is:issue is:open assignee:@me")
+	if err != nil {
+		return "", err
+	}
+	summaries := make([]IssueSummary, 0, len(issues))
+	for _, issue := range issues {
+		owner, repo := repoFromURL(issue.GetRepositoryURL())
+		summary := IssueSummary{
+			Repo:         owner + "/" + repo,
+			Number:       issue.GetNumber(),
+			Title:        issue.GetTitle(),
+			URL:          issue.GetHTMLURL(),
+			Author:       issue.GetUser().GetLogin(),
+			Age:          formatAge(time.Since(issue.GetCreatedAt().Time)),
+			LastActivity: formatAge(time.Since(issue.GetUpdatedAt().Time)) + " ago",
+		}
+		for _, label := range issue.Labels {
+			summary.Labels = append(summary.Labels, label.GetName())
+		}
+		summaries = append(summaries, summary)
+	}
+	return marshalResults(summaries, truncated, g.maxResults)
+}
+
+// listNotifications lists the unread notifications since the given time,
+// which include mentions of me and my teams.
+func (g *Github) listNotifications(ctx context.Context, since time.Time) (string, error) {
+	opts := &github.NotificationListOptions{
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: min(g.maxResults, maxPerPage)},
+	}
+	notifications := make([]*github.Notification, 0)
+	truncated := false
+	for {
+		page, resp, err := g.client.Activity.ListNotifications(ctx, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list notifications: %v", err)
+		}
+		notifications = append(notifications, page...)
+		if len(notifications) >= g.maxResults {
+			truncated = len(notifications) > g.maxResults || resp.NextPage != 0
+			notifications = notifications[:g.maxResults]
+			break
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	summaries := make([]NotificationSummary, 0, len(notifications))
+	for _, notification := range notifications {
+		summaries = append(summaries, NotificationSummary{
+			Repo:    notification.GetRepository().GetFullName(),
+			Type:    notification.GetSubject().GetType(),
+			Title:   notification.GetSubject().GetTitle(),
+			Reason:  notification.GetReason(),
+			URL:     htmlURL(notification.GetSubject().GetURL()),
+			Updated: formatAge(time.Since(notification.GetUpdatedAt().Time)) + " ago",
+		})
+	}
+	return marshalResults(summaries, truncated, g.maxResults)
+}
+
+// listMyPRsNeedingAttention lists my open pull requests with failing checks
+// or changes requested. Only the pull requests looked at in detail can be
+// checked, so the list is truncated when there are more.
+func (g *Github) listMyPRsNeedingAttention(ctx context.Context) (string, error) {
+	summaries, truncated, err := g.searchPullRequests(ctx, "is:pull-request is:open author:@me")
+	if err != nil {
+		return "", err
+	}
+	if len(summaries) > maxPullRequests {
+		summaries, truncated = summaries[:maxPullRequests], true
+	}
+	attention := make([]PullRequestSummary, 0)
+	for _, summary := range summaries {
+		if summary.CIStatus == "failure" || summary.ReviewDecision == "changes_requested" {
+			attention = append(attention, summary)
+		}
+	}
+	return marshalResults(attention, truncated, min(g.maxResults, maxPullRequests))
+}
+
+// listApprovedPRsUpdated lists the open pull requests I approved that have
+// changed since my approval, and since the given time, so may need another
+// look.
+func (g *Github) listApprovedPRsUpdated(ctx context.Context, since time.Time) (string, error) {
+	user, err := g.me(ctx)
+	if err != nil {
+		return "", err
+	}
+	issues, truncated, err := g.search(ctx, "is:pull-request is:open reviewed-by:@me updated:>="+since.UTC().Format("2006-01-02T15:04:05Z"))
+	if err != nil {
+		return "", err
+	}
+	if len(issues) > maxPullRequests {
+		issues, truncated = issues[:maxPullRequests], true
+	}
+
+	results := make([]*ApprovedPullRequest, len(issues))
+	forEachLimited(len(issues), func(i int) {
+		issue := issues[i]
+		owner, repo := repoFromURL(issue.GetRepositoryURL())
+		approvedAt, ok := g.approvedAt(ctx, owner, repo, issue.GetNumber(), user.GetID())
+		if !ok || !issue.GetUpdatedAt().After(approvedAt) {
+			return
+		}
+		summary := pullRequestSummary(issue)
+		g.addDetails(ctx, &summary)
+		results[i] = &ApprovedPullRequest{
+			PullRequestSummary: summary,
+			Approved:           formatAge(time.Since(approvedAt)) + " ago",
+		}
+	})
+
+	approved := make([]*ApprovedPullRequest, 0)
+	for _, result := range results {
+		if result != nil {
+			approved = append(approved, result)
+		}
+	}
+	return marshalResults(approved, truncated, min(g.maxResults, maxPullRequests))
+}
+
+// approvedAt returns when the user approved the pull request, when their
+// latest review is still an approval.
+func (g *Github) approvedAt(ctx context.Context, owner string, repo string, number int, userID int64) (time.Time, bool) {
+	reviews, _, err := g.client.PullRequests.ListReviews(ctx, owner, repo, number, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return time.Time{}, false
+	}
+	var latest *github.PullRequestReview
+	for _, review := range reviews {
+		if review.GetUser().GetID() != userID || review.GetState() == "COMMENTED" {
+			continue
+		}
+		latest = review
+	}
+	if latest == nil || latest.GetState() != "APPROVED" {
+		return time.Time{}, false
+	}
+	return latest.GetSubmittedAt().Time, true
+}
+
+// htmlURL turns an API URL from a notification into the page for people,
+// https://api.github.com/repos/owner/name/pulls/1 becomes
+// https://github.com/owner/name/pull/1.
+func htmlURL(apiURL string) string {
+	if apiURL == "" {
+		return ""
+	}
+	url := strings.Replace(apiURL, "://api.github.com/repos/", "://github.com/", 1)
+	url = strings.Replace(url, "/api/v3/repos/", "/", 1)
+	return strings.Replace(url, "/pulls/", "/pull/", 1)
+}
+
+// lastRun returns when the last briefing started. Before the first one is
+// recorded it falls back to the same time on the previous weekday.
+func (g *Github) lastRun(now time.Time) time.Time {
+	data, err := os.ReadFile(g.lastRunFile)
+	if err == nil {
+		if lastRun, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); err == nil {
+			return lastRun
+		}
+	}
+	return previousWeekday(now.In(g.location))
+}
+
+// RecordRun records when a briefing started, so the next one looks from
+// there.
+func (g *Github) RecordRun(start time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(g.lastRunFile), 0755); err != nil {
+		return fmt.Errorf("failed to create the last run directory: %v", err)
+	}
+	if err := os.WriteFile(g.lastRunFile, []byte(start.Format(time.RFC3339)), 0644); err != nil {
+		return fmt.Errorf("failed to record the last run: %v", err)
+	}
+	return nil
+}
+
+// previousWeekday returns the same time on the weekday before, so Monday
+// looks back to Friday.
+func previousWeekday(now time.Time) time.Time {
+	previous := now.AddDate(0, 0, -1)
+	for previous.Weekday() == time.Saturday || previous.Weekday() == time.Sunday {
+		previous = previous.AddDate(0, 0, -1)
+	}
+	return previous
+}
+
+// parseSince reads an RFC 3339 time, or a date as the start of the day in
+// location.
+func parseSince(value string, location *time.Location) (time.Time, error) {
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, location)
+}
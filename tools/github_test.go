@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachLimited(t *testing.T) {
+	var mu sync.Mutex
+	running, most := 0, 0
+	called := make([]bool, 3*maxConcurrentRequests)
+	forEachLimited(len(called), func(i int) {
+		mu.Lock()
+		running++
+		most = max(most, running)
+		called[i] = true
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if most > maxConcurrentRequests {
+		t.Errorf("%d ran at once, want at most %d", most, maxConcurrentRequests)
+	}
+	for i, ok := range called {
+		if !ok {
+			t.Errorf("%d was not called", i)
+		}
+	}
+}
+
+func TestLastRun(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Github{location: tokyo, lastRunFile: filepath.Join(t.TempDir(), "context", "last_run")}
+	// Monday morning in Tokyo is still Sunday in UTC
+	now := time.Date(2025, time.January, 6, 8, 0, 0, 0, tokyo)
+
+	if got, want := g.lastRun(now), time.Date(2025, time.January, 3, 8, 0, 0, 0, tokyo); !got.Equal(want) {
+		t.Errorf("before a run last run is %v, want the previous weekday %v", got, want)
+	}
+
+	start := time.Date(2025, time.January, 3, 9, 15, 0, 0, tokyo)
+	if err := g.RecordRun(start); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.lastRun(now); !got.Equal(start) {
+		t.Errorf("last run is %v, want %v", got, start)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2025-01-06", time.Date(2025, time.January, 6, 0, 0, 0, 0, tokyo)},
+		{"2025-01-06T09:00:00Z", time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := parseSince(test.value, tokyo)
+		if err != nil {
+			t.Fatalf("%s: %v", test.value, err)
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%s is %v, want %v", test.value, got, test.want)
+		}
+	}
+	if _, err := parseSince("yesterday", tokyo); err == nil {
+		t.Error("yesterday parsed")
+	}
+}
@@ -7,26 +7,51 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 )
 
+// maxPageSize is how many issues are asked for in each request.
+const maxPageSize = 50
+
+// maxOpenIssues bounds how many of my open issues are read to find the urgent
+// ones, well beyond what anyone has assigned.
+const maxOpenIssues = 500
+
 type Linear struct {
-	token string
+	token      string
+	maxResults int
+	location   *time.Location
+	staleAfter time.Duration
+	confirm    *ConfirmationGate
+
+	// viewerID is the ID of the user the token belongs to, looked up once
+	viewerID string
+	mu       sync.Mutex
 }
 
-func NewLinear(token string) *Linear {
+// NewLinear creates the Linear tool. Lists stop after maxResults. Due dates
+// are days in the location, and my issues untouched for staleAfter are stale.
+// Changes to Linear only happen when confirm allows them.
+func NewLinear(token string, maxResults int, location *time.Location, staleAfter time.Duration, confirm *ConfirmationGate) *Linear {
 	return &Linear{
-		token: token,
+		token:      token,
+		maxResults: maxResults,
+		location:   location,
+		staleAfter: staleAfter,
+		confirm:    confirm,
 	}
 }
 
 type graphqlRequest struct {
-	Query string `json:"query"`
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
 }
 
 type teamResponse struct {
+	graphqlErrors
 	Data struct {
 		Teams struct {
 			Nodes []struct {
@@ -37,35 +62,222 @@ type teamResponse struct {
 	} `json:"data"`
 }
 
-type issueResponse struct {
+type viewerResponse struct {
+	graphqlErrors
 	Data struct {
-		Teams struct {
-			Nodes []struct {
-				Name   string `json:"name"`
-				Issues struct {
-					Nodes []struct {
-						ID    string `json:"id"`
-						Title string `json:"title"`
-						State struct {
-							Name  string `json:"name"`
-							Color string `json:"color"`
-							Type  string `json:"type"`
-						} `json:"state"`
-						Priority int    `json:"priority"`
-						URL      string `json:"url"`
-						Assignee struct {
-							Name string `json:"name"`
-						} `json:"assignee"`
-						CreatedAt string `json:"createdAt"`
-					} `json:"nodes"`
-				} `json:"issues"`
-			} `json:"nodes"`
-		} `json:"teams"`
+		Viewer struct {
+			ID string `json:"id"`
+		} `json:"viewer"`
+	} `json:"data"`
+}
+
+type issueCreateResponse struct {
+	graphqlErrors
+	Data struct {
+		IssueCreate struct {
+			Success bool      `json:"success"`
+			Issue   issueNode `json:"issue"`
+		} `json:"issueCreate"`
+	} `json:"data"`
+}
+
+// LinearIssue is the part of an issue worth reading in a briefing.
+type LinearIssue struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Team       string `json:"team"`
+	State      string `json:"state"`
+	// StateType is Linear's kind of state, such as started or completed
+	StateType string `json:"state_type"`
+	Priority  string `json:"priority,omitempty"`
+	Assignee  string `json:"assignee,omitempty"`
+	URL       string `json:"url"`
+}
+
+// issueNode is an issue as the issues queries return it. The fields after
+// the URL are only asked for with myIssueFields.
+type issueNode struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Team       struct {
+		Name string `json:"name"`
+	} `json:"team"`
+	State struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"state"`
+	Priority int `json:"priority"`
+	Assignee *struct {
+		Name string `json:"name"`
+	} `json:"assignee"`
+	URL string `json:"url"`
+
+	DueDate       string     `json:"dueDate"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	SLABreachesAt *time.Time `json:"slaBreachesAt"`
+	// Relations are the issues this one relates to, inverse relations the
+	// issues relating to this one, so an inverse blocks relation blocks it
+	Relations struct {
+		Nodes []struct {
+			Type         string       `json:"type"`
+			RelatedIssue relatedIssue `json:"relatedIssue"`
+		} `json:"nodes"`
+	} `json:"relations"`
+	InverseRelations struct {
+		Nodes []struct {
+			Type  string       `json:"type"`
+			Issue relatedIssue `json:"issue"`
+		} `json:"nodes"`
+	} `json:"inverseRelations"`
+}
+
+type relatedIssue struct {
+	Identifier string `json:"identifier"`
+	State      struct {
+		Type string `json:"type"`
+	} `json:"state"`
+}
+
+// issueFields are the fields of issueNode, for the issues queries.
+const issueFields = `
+	identifier
+	title
+	team {
+		name
+	}
+	state {
+		name
+		type
+	}
+	priority
+	assignee {
+		name
+	}
+	url
+`
+
+// linearPriorities are the names of Linear's priority numbers.
+var linearPriorities = []string{"", "urgent", "high", "medium", "low"}
+
+func (node issueNode) summary() LinearIssue {
+	issue := LinearIssue{
+		Identifier: node.Identifier,
+		Title:      node.Title,
+		Team:       node.Team.Name,
+		State:      node.State.Name,
+		StateType:  node.State.Type,
+		URL:        node.URL,
+	}
+	if node.Priority > 0 && node.Priority < len(linearPriorities) {
+		issue.Priority = linearPriorities[node.Priority]
+	}
+	if node.Assignee != nil {
+		issue.Assignee = node.Assignee.Name
+	}
+	return issue
+}
+
+// graphqlErrors are the errors of a GraphQL response, which come back with
+// status 200.
+type graphqlErrors struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e graphqlErrors) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Linear API returned an error: %s", e.Errors[0].Message)
+}
+
+// viewer returns the ID of the user the token belongs to. It is cached once
+// found, failures are tried again next time.
+func (l *Linear) viewer(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.viewerID != "" {
+		return l.viewerID, nil
+	}
+
+	body, err := l.makeRequest(ctx, `
+	query Viewer {
+		viewer {
+			id
+		}
+	}
+	`, nil)
+	if err != nil {
+		return "", err
+	}
+	var viewer viewerResponse
+	if err := json.Unmarshal(body, &viewer); err != nil {
+		return "", fmt.Errorf("failed to decode the Linear user: %v", err)
+	}
+	if err := viewer.err(); err != nil {
+		return "", err
+	}
+	l.viewerID = viewer.Data.Viewer.ID
+	return l.viewerID, nil
+}
+
+// ViewerID returns the ID of the user the token belongs to.
+func (l *Linear) ViewerID(ctx context.Context) (string, error) {
+	return l.viewer(ctx)
+}
+
+// connectionPage is a page of a GraphQL connection, keyed by the field the
+// query reads it from, such as issues.
+type connectionPage[T any] struct {
+	graphqlErrors
+	Data map[string]struct {
+		Nodes    []T `json:"nodes"`
+		PageInfo struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
 	} `json:"data"`
 }
 
-func (l *Linear) makeRequest(ctx context.Context, query string) ([]byte, error) {
-	reqBody, err := json.Marshal(graphqlRequest{Query: query})
+// paginate follows the pages of the connection the query reads from field up
+// to the limit, and reports whether there were more. The query takes the page
+// with $first and $after, as well as the given variables.
+func paginate[T any](ctx context.Context, l *Linear, field string, query string, variables map[string]any, limit int) ([]T, bool, error) {
+	nodes := make([]T, 0)
+	for {
+		variables["first"] = min(limit-len(nodes), maxPageSize)
+		body, err := l.makeRequest(ctx, query, variables)
+		if err != nil {
+			return nil, false, err
+		}
+		var page connectionPage[T]
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, false, fmt.Errorf("failed to decode %s: %v", field, err)
+		}
+		if err := page.err(); err != nil {
+			return nil, false, err
+		}
+		connection := page.Data[field]
+		nodes = append(nodes, connection.Nodes...)
+		if !connection.PageInfo.HasNextPage {
+			return nodes, false, nil
+		}
+		if len(nodes) >= limit {
+			return nodes, true, nil
+		}
+		variables["after"] = connection.PageInfo.EndCursor
+	}
+}
+
+// listIssues follows the pages of an issues query up to the limit, and
+// reports whether there were more.
+func (l *Linear) listIssues(ctx context.Context, query string, variables map[string]any, limit int) ([]issueNode, bool, error) {
+	return paginate[issueNode](ctx, l, "issues", query, variables, limit)
+}
+
+func (l *Linear) makeRequest(ctx context.Context, query string, variables map[string]any) ([]byte, error) {
+	reqBody, err := json.Marshal(graphqlRequest{Query: query, Variables: variables})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
@@ -104,98 +316,206 @@ func (l *Linear) Run(ctx context.Context, arguments json.RawMessage) (string, er
 
 	switch inputs.Action {
 	case "get_my_teams_in_review_issues":
-		return l.getMyTeamsInReviewIssues(ctx, inputs.Teams, inputs.Name)
+		return l.getMyTeamsInReviewIssues(ctx, inputs.Teams)
 	case "get_my_issues":
-		return l.getMyIssues(ctx, inputs.Name)
+		return l.getMyIssues(ctx)
+	case "get_cycle_progress":
+		return l.getCycleProgress(ctx, inputs.Teams)
+	case "get_project_progress":
+		return l.getProjectProgress(ctx, inputs.Teams)
+	case "comment_on_issue", "move_issue", "assign_me":
+		if inputs.Issue == "" {
+			return "", &InvalidToolArgumentsError{
+				ToolName: l.Name(),
+				Message:  inputs.Action + " needs an issue",
+			}
+		}
+		switch {
+		case inputs.Action == "assign_me":
+			return l.assignMe(ctx, inputs.Issue)
+		case inputs.Action == "move_issue" && inputs.State != "":
+			return l.moveIssue(ctx, inputs.Issue, inputs.State)
+		case inputs.Action == "comment_on_issue" && inputs.Comment != "":
+			return l.commentOnIssue(ctx, inputs.Issue, inputs.Comment)
+		}
+		return "", &InvalidToolArgumentsError{
+			ToolName: l.Name(),
+			Message:  "move_issue needs a state and comment_on_issue needs a comment",
+		}
+	case "create_issue":
+		if inputs.Title == "" || len(inputs.Teams) == 0 {
+			return "", &InvalidToolArgumentsError{
+				ToolName: l.Name(),
+				Message:  "create_issue needs a title and a team",
+			}
+		}
+		return l.write("create_issue", fmt.Sprintf("create an issue in %s: %q", inputs.Teams[0], inputs.Title), func() (string, error) {
+			return l.createIssue(ctx, inputs.Teams[0], inputs.Title, inputs.Description)
+		})
 	default:
 		return "", fmt.Errorf("invalid action: %s", inputs.Action)
 	}
 }
 
-func (l *Linear) getMyTeamsInReviewIssues(ctx context.Context, teams []string, name string) (string, error) {
+// needTeams checks an action that reads from teams was given some, Linear
+// reads a missing list as null.
+func (l *Linear) needTeams(action string, teams []string) error {
+	if len(teams) == 0 {
+		return &InvalidToolArgumentsError{
+			ToolName: l.Name(),
+			Message:  action + " needs at least one team",
+		}
+	}
+	return nil
+}
+
+// getMyTeamsInReviewIssues lists the teams' issues in review that other
+// people are working on.
+func (l *Linear) getMyTeamsInReviewIssues(ctx context.Context, teams []string) (string, error) {
+	if err := l.needTeams("get_my_teams_in_review_issues", teams); err != nil {
+		return "", err
+	}
 	fmt.Println("Getting my teams in review issues.")
 
-	teamKeys := make([]string, 0)
-	for _, team := range teams {
-		teamKeys = append(teamKeys, fmt.Sprintf(`"%s"`, team))
+	me, err := l.viewer(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	issuesQuery := fmt.Sprintf(`
-	query {
+	nodes, truncated, err := l.listIssues(ctx, `
+	query InReviewIssues($first: Int!, $after: String, $teams: [String!]!, $me: ID!) {
 		issues(
-			first: 40,
-			orderBy: updatedAt, 
-			filter: { 
-				team: { key: { in: [%s] } },
-				state: { name: { in: "In Review" }}
-				assignee: { name: {nin: "%s"}}
+			first: $first,
+			after: $after,
+			orderBy: updatedAt,
+			filter: {
+				team: { key: { in: $teams } },
+				state: { name: { eq: "In Review" } },
+				assignee: { id: { neq: $me } }
 			}
 		) {
-			nodes {
-				identifier
-				title
-				team {
-					name
-				}
-				state {
-					name
-					type
-				}
-				assignee {
-					name
-				}
-				url
+			nodes {`+issueFields+`}
+			pageInfo {
+				hasNextPage
+				endCursor
 			}
 		}
 	}
-	`, strings.Join(teamKeys, ", "), name)
-
-	issuesBody, err := l.makeRequest(ctx, issuesQuery)
+	`, map[string]any{"teams": teams, "me": me}, l.maxResults)
 	if err != nil {
 		return "", err
 	}
+	issues := make([]LinearIssue, 0, len(nodes))
+	for _, node := range nodes {
+		issues = append(issues, node.summary())
+	}
 
-	return string(issuesBody), nil
+	return marshalResults(issues, truncated, l.maxResults)
 }
 
-func (l *Linear) getMyIssues(ctx context.Context, name string) (string, error) {
+// getMyIssues lists my open issues, most urgent first. All of them are
+// classified before the list is cut to the limit, so old issues that are
+// stale or overdue aren't the ones left out.
+func (l *Linear) getMyIssues(ctx context.Context) (string, error) {
 	fmt.Println("Getting my issues.")
 
-	issuesQuery := fmt.Sprintf(`
-	query {
+	me, err := l.viewer(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	nodes, truncated, err := l.listIssues(ctx, `
+	query MyIssues($first: Int!, $after: String, $me: ID!) {
 		issues(
-			first: 40,
-			orderBy: updatedAt,	
-			filter: { 
-				assignee: { name: {in: "%s"}}
+			first: $first,
+			after: $after,
+			orderBy: updatedAt,
+			filter: {
+				assignee: { id: { eq: $me } },
+				state: { type: { nin: ["completed", "canceled"] } }
 			}
 		) {
+			nodes {`+myIssueFields+`}
+			pageInfo {
+				hasNextPage
+				endCursor
+			}
+		}
+	}
+	`, map[string]any{"me": me}, maxOpenIssues)
+	if err != nil {
+		return "", err
+	}
+
+	issues := l.myIssues(nodes, time.Now())
+	if len(issues) > l.maxResults {
+		issues, truncated = issues[:l.maxResults], true
+	}
+	return marshalResults(issues, truncated, l.maxResults)
+}
+
+// createIssue creates an issue in the team with the given key.
+func (l *Linear) createIssue(ctx context.Context, team string, title string, description string) (string, error) {
+	fmt.Println("Creating an issue in " + team + ".")
+
+	teamBody, err := l.makeRequest(ctx, `
+	query TeamID($key: String!) {
+		teams(filter: { key: { eq: $key } }) {
 			nodes {
-				identifier
-				title
-				team {
-					name
-				}
-				state {
-					name
-					type
-				}
-				priority
-				assignee {
-					name
-				}
-				url
+				id
+				name
 			}
 		}
 	}
-	`, name)
+	`, map[string]any{"key": team})
+	if err != nil {
+		return "", err
+	}
+	var teams teamResponse
+	if err := json.Unmarshal(teamBody, &teams); err != nil {
+		return "", fmt.Errorf("failed to decode teams: %v", err)
+	}
+	if err := teams.err(); err != nil {
+		return "", err
+	}
+	if len(teams.Data.Teams.Nodes) == 0 {
+		return "", &InvalidToolArgumentsError{
+			ToolName: l.Name(),
+			Message:  fmt.Sprintf("team %s not found", team),
+		}
+	}
 
-	issuesBody, err := l.makeRequest(ctx, issuesQuery)
+	issueBody, err := l.makeRequest(ctx, `
+	mutation CreateIssue($input: IssueCreateInput!) {
+		issueCreate(input: $input) {
+			success
+			issue {`+issueFields+`}
+		}
+	}
+	`, map[string]any{"input": map[string]any{
+		"teamId":      teams.Data.Teams.Nodes[0].ID,
+		"title":       title,
+		"description": description,
+	}})
 	if err != nil {
 		return "", err
 	}
+	var created issueCreateResponse
+	if err := json.Unmarshal(issueBody, &created); err != nil {
+		return "", fmt.Errorf("failed to decode the created issue: %v", err)
+	}
+	if err := created.err(); err != nil {
+		return "", err
+	}
+	if !created.Data.IssueCreate.Success {
+		return "", fmt.Errorf("Linear did not create the issue")
+	}
 
-	return string(issuesBody), nil
+	jsonResponse, err := json.Marshal(created.Data.IssueCreate.Issue.summary())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(jsonResponse), nil
 }
 
 func (l *Linear) Name() string {
@@ -205,13 +525,17 @@ func (l *Linear) Name() string {
 func (l *Linear) ToolDefinition() *anthropic.ToolParam {
 	return &anthropic.ToolParam{
 		Name:        l.Name(),
-		Description: anthropic.String("Get status of all tasks for your team in Linear"),
+		Description: anthropic.String("Get status of all tasks for your team in Linear, and how the team's cycle and projects are going"),
 		InputSchema: GenerateSchema[LinearToolInputs](),
 	}
 }
 
 type LinearToolInputs struct {
-	Action string   `json:"action" jsonschema_description:"The action to perform (get_my_teams_in_review_issues, get_my_issues)"`
-	Teams  []string `json:"teams" jsonschema_description:"The teams to get issues from"`
-	Name   string   `json:"name" jsonschema_description:"Your name"`
+	Action      string   `json:"action" jsonschema_description:"The action to perform (get_my_teams_in_review_issues, get_my_issues, get_cycle_progress, get_project_progress, comment_on_issue, move_issue, assign_me, create_issue). comment_on_issue, move_issue, assign_me and create_issue change Linear, only use them when asked to, and each change may need to be confirmed"`
+	Teams       []string `json:"teams" jsonschema_description:"The teams to get issues, cycles or projects from, create_issue uses the first team"`
+	Title       string   `json:"title,omitempty" jsonschema_description:"The title of the issue to create"`
+	Description string   `json:"description,omitempty" jsonschema_description:"The markdown description of the issue to create"`
+	Issue       string   `json:"issue,omitempty" jsonschema_description:"The identifier of the issue to comment on, move or assign, such as ENG-123"`
+	State       string   `json:"state,omitempty" jsonschema_description:"The name of the state to move the issue to, such as In Progress"`
+	Comment     string   `json:"comment,omitempty" jsonschema_description:"The markdown comment to add to the issue"`
 }
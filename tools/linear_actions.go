@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// notConfirmed is the result of a change the user didn't allow, so the model
+// knows not to try again.
+const notConfirmed = "The change was not made, it was not confirmed."
+
+// LinearComment is a comment the tool added to an issue.
+type LinearComment struct {
+	Issue string `json:"issue"`
+	URL   string `json:"url"`
+}
+
+type issueLookupResponse struct {
+	graphqlErrors
+	Data struct {
+		Issue *struct {
+			ID         string `json:"id"`
+			Identifier string `json:"identifier"`
+			Title      string `json:"title"`
+			Team       struct {
+				States struct {
+					Nodes []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	} `json:"data"`
+}
+
+type commentCreateResponse struct {
+	graphqlErrors
+	Data struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+			Comment struct {
+				URL string `json:"url"`
+			} `json:"comment"`
+		} `json:"commentCreate"`
+	} `json:"data"`
+}
+
+type issueUpdateResponse struct {
+	graphqlErrors
+	Data struct {
+		IssueUpdate struct {
+			Success bool      `json:"success"`
+			Issue   issueNode `json:"issue"`
+		} `json:"issueUpdate"`
+	} `json:"data"`
+}
+
+// write runs a change to Linear once the gate confirms it.
+func (l *Linear) write(action string, change string, run func() (string, error)) (string, error) {
+	if !l.confirm.Confirm(action, change) {
+		fmt.Println("Not allowed to " + change + ".")
+		return notConfirmed, nil
+	}
+	return run()
+}
+
+// lookupIssue finds an issue by its identifier, such as ENG-123, with the
+// states of its team.
+func (l *Linear) lookupIssue(ctx context.Context, identifier string) (*issueLookupResponse, error) {
+	body, err := l.makeRequest(ctx, `
+	query Issue($id: String!) {
+		issue(id: $id) {
+			id
+			identifier
+			title
+			team {
+				states {
+					nodes {
+						id
+						name
+					}
+				}
+			}
+		}
+	}
+	`, map[string]any{"id": identifier})
+	if err != nil {
+		return nil, err
+	}
+	var issue issueLookupResponse
+	if err := json.Unmarshal(body, &issue); err != nil {
+		return nil, fmt.Errorf("failed to decode the issue: %v", err)
+	}
+	notFound := &InvalidToolArgumentsError{
+		ToolName: l.Name(),
+		Message:  fmt.Sprintf("issue %s not found", identifier),
+	}
+	if err := issue.err(); err != nil {
+		// Linear answers an unknown issue with an error, other errors such as
+		// auth and rate limits are passed on
+		if issue.Data.Issue == nil && strings.Contains(strings.ToLower(err.Error()), "not found") {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	if issue.Data.Issue == nil {
+		return nil, notFound
+	}
+	return &issue, nil
+}
+
+func (l *Linear) commentOnIssue(ctx context.Context, identifier string, comment string) (string, error) {
+	lookup, err := l.lookupIssue(ctx, identifier)
+	if err != nil {
+		return "", err
+	}
+	issue := lookup.Data.Issue
+
+	return l.write("comment_on_issue", fmt.Sprintf("comment on %s %q: %q", issue.Identifier, issue.Title, comment), func() (string, error) {
+		fmt.Println("Commenting on " + issue.Identifier + ".")
+		body, err := l.makeRequest(ctx, `
+		mutation CommentOnIssue($input: CommentCreateInput!) {
+			commentCreate(input: $input) {
+				success
+				comment {
+					url
+				}
+			}
+		}
+		`, map[string]any{"input": map[string]any{"issueId": issue.ID, "body": comment}})
+		if err != nil {
+			return "", err
+		}
+		var created commentCreateResponse
+		if err := json.Unmarshal(body, &created); err != nil {
+			return "", fmt.Errorf("failed to decode the comment: %v", err)
+		}
+		if err := created.err(); err != nil {
+			return "", err
+		}
+		if !created.Data.CommentCreate.Success {
+			return "", fmt.Errorf("Linear did not add the comment")
+		}
+
+		jsonResponse, err := json.Marshal(LinearComment{Issue: issue.Identifier, URL: created.Data.CommentCreate.Comment.URL})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal response: %v", err)
+		}
+		return string(jsonResponse), nil
+	})
+}
+
+// moveIssue moves an issue to the state of its team with the given name,
+// ignoring case.
+func (l *Linear) moveIssue(ctx context.Context, identifier string, state string) (string, error) {
+	lookup, err := l.lookupIssue(ctx, identifier)
+	if err != nil {
+		return "", err
+	}
+	issue := lookup.Data.Issue
+
+	stateID := ""
+	names := make([]string, 0)
+	for _, node := range issue.Team.States.Nodes {
+		names = append(names, node.Name)
+		if strings.EqualFold(node.Name, state) {
+			stateID, state = node.ID, node.Name
+		}
+	}
+	if stateID == "" {
+		return "", &InvalidToolArgumentsError{
+			ToolName: l.Name(),
+			Message:  fmt.Sprintf("state %s not found, the states of %s's team are: %s", state, issue.Identifier, strings.Join(names, ", ")),
+		}
+	}
+
+	return l.write("move_issue", fmt.Sprintf("move %s %q to %s", issue.Identifier, issue.Title, state), func() (string, error) {
+		fmt.Println("Moving " + issue.Identifier + " to " + state + ".")
+		return l.updateIssue(ctx, issue.ID, map[string]any{"stateId": stateID})
+	})
+}
+
+func (l *Linear) assignMe(ctx context.Context, identifier string) (string, error) {
+	lookup, err := l.lookupIssue(ctx, identifier)
+	if err != nil {
+		return "", err
+	}
+	issue := lookup.Data.Issue
+	me, err := l.viewer(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return l.write("assign_me", fmt.Sprintf("assign you to %s %q", issue.Identifier, issue.Title), func() (string, error) {
+		fmt.Println("Assigning me to " + issue.Identifier + ".")
+		return l.updateIssue(ctx, issue.ID, map[string]any{"assigneeId": me})
+	})
+}
+
+func (l *Linear) updateIssue(ctx context.Context, id string, input map[string]any) (string, error) {
+	body, err := l.makeRequest(ctx, `
+	mutation UpdateIssue($id: String!, $input: IssueUpdateInput!) {
+		issueUpdate(id: $id, input: $input) {
+			success
+			issue {`+issueFields+`}
+		}
+	}
+	`, map[string]any{"id": id, "input": input})
+	if err != nil {
+		return "", err
+	}
+	var updated issueUpdateResponse
+	if err := json.Unmarshal(body, &updated); err != nil {
+		return "", fmt.Errorf("failed to decode the updated issue: %v", err)
+	}
+	if err := updated.err(); err != nil {
+		return "", err
+	}
+	if !updated.Data.IssueUpdate.Success {
+		return "", fmt.Errorf("Linear did not update the issue")
+	}
+
+	jsonResponse, err := json.Marshal(updated.Data.IssueUpdate.Issue.summary())
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(jsonResponse), nil
+}
@@ -0,0 +1,349 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// CycleProgress is how a team's active cycle is going.
+type CycleProgress struct {
+	Team  string `json:"team"`
+	Cycle string `json:"cycle"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// DaysRemaining counts part days as whole days
+	DaysRemaining int `json:"days_remaining"`
+	// ScopePoints and CompletedPoints are the estimates of the cycle's issues
+	ScopePoints     float64 `json:"scope_points"`
+	CompletedPoints float64 `json:"completed_points"`
+	Issues          int     `json:"issues"`
+	CompletedIssues int     `json:"completed_issues"`
+	// Progress is Linear's percentage of the cycle done, counting issues in
+	// progress as partly done
+	Progress int           `json:"progress"`
+	AtRisk   []AtRiskIssue `json:"at_risk,omitempty"`
+	// Truncated says when there were more issues at risk than were read
+	Truncated string `json:"truncated,omitempty"`
+}
+
+// AtRiskIssue is an issue that may not be done by the end of its cycle.
+type AtRiskIssue struct {
+	LinearIssue
+	Reason string `json:"reason"`
+}
+
+// CyclesProgress is the output of get_cycle_progress.
+type CyclesProgress struct {
+	Cycles []CycleProgress `json:"cycles"`
+	// NoActiveCycle are the teams not running a cycle now
+	NoActiveCycle []string `json:"no_active_cycle,omitempty"`
+}
+
+// ProjectProgress is how a project is going against its target date.
+type ProjectProgress struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Teams  []string `json:"teams"`
+	Status string   `json:"status"`
+	Lead   string   `json:"lead,omitempty"`
+	// Progress is the percentage of the project done
+	Progress   int                `json:"progress"`
+	TargetDate string             `json:"target_date,omitempty"`
+	Overdue    bool               `json:"overdue,omitempty"`
+	Milestones []MilestoneSummary `json:"milestones,omitempty"`
+}
+
+// MilestoneSummary is a project milestone and its target date.
+type MilestoneSummary struct {
+	Name       string `json:"name"`
+	TargetDate string `json:"target_date,omitempty"`
+	Progress   int    `json:"progress"`
+	Overdue    bool   `json:"overdue,omitempty"`
+}
+
+type cycleNode struct {
+	ID                         string    `json:"id"`
+	Number                     int       `json:"number"`
+	Name                       string    `json:"name"`
+	StartsAt                   time.Time `json:"startsAt"`
+	EndsAt                     time.Time `json:"endsAt"`
+	Progress                   float64   `json:"progress"`
+	ScopeHistory               []float64 `json:"scopeHistory"`
+	CompletedScopeHistory      []float64 `json:"completedScopeHistory"`
+	IssueCountHistory          []float64 `json:"issueCountHistory"`
+	CompletedIssueCountHistory []float64 `json:"completedIssueCountHistory"`
+}
+
+type cyclesResponse struct {
+	graphqlErrors
+	Data struct {
+		Teams struct {
+			Nodes []struct {
+				Key         string     `json:"key"`
+				Name        string     `json:"name"`
+				ActiveCycle *cycleNode `json:"activeCycle"`
+			} `json:"nodes"`
+		} `json:"teams"`
+	} `json:"data"`
+}
+
+type projectNode struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Status struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"status"`
+	Lead *struct {
+		Name string `json:"name"`
+	} `json:"lead"`
+	Teams struct {
+		Nodes []struct {
+			Key  string `json:"key"`
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"teams"`
+	Progress          float64 `json:"progress"`
+	TargetDate        string  `json:"targetDate"`
+	ProjectMilestones struct {
+		Nodes []struct {
+			Name       string  `json:"name"`
+			TargetDate string  `json:"targetDate"`
+			Progress   float64 `json:"progress"`
+		} `json:"nodes"`
+	} `json:"projectMilestones"`
+}
+
+// getCycleProgress summarises the active cycle of each team.
+func (l *Linear) getCycleProgress(ctx context.Context, teams []string) (string, error) {
+	if err := l.needTeams("get_cycle_progress", teams); err != nil {
+		return "", err
+	}
+	fmt.Println("Getting cycle progress.")
+
+	body, err := l.makeRequest(ctx, `
+	query CycleProgress($teams: [String!]!) {
+		teams(filter: { key: { in: $teams } }) {
+			nodes {
+				key
+				name
+				activeCycle {
+					id
+					number
+					name
+					startsAt
+					endsAt
+					progress
+					scopeHistory
+					completedScopeHistory
+					issueCountHistory
+					completedIssueCountHistory
+				}
+			}
+		}
+	}
+	`, map[string]any{"teams": teams})
+	if err != nil {
+		return "", err
+	}
+	var response cyclesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to decode cycles: %v", err)
+	}
+	if err := response.err(); err != nil {
+		return "", err
+	}
+
+	progress := CyclesProgress{Cycles: make([]CycleProgress, 0)}
+	now := time.Now()
+	for _, team := range response.Data.Teams.Nodes {
+		if team.ActiveCycle == nil {
+			progress.NoActiveCycle = append(progress.NoActiveCycle, team.Name)
+			continue
+		}
+		cycle := cycleProgress(team.Name, *team.ActiveCycle, now, l.location)
+		if pastHalfway(*team.ActiveCycle, now) {
+			notStarted, truncated, err := l.listIssues(ctx, `
+			query CycleNotStarted($cycle: ID!, $first: Int!, $after: String) {
+				issues(
+					filter: {
+						cycle: { id: { eq: $cycle } },
+						state: { type: { in: ["triage", "backlog", "unstarted"] } }
+					},
+					first: $first,
+					after: $after
+				) {
+					nodes {`+issueFields+`}
+					pageInfo {
+						hasNextPage
+						endCursor
+					}
+				}
+			}
+			`, map[string]any{"cycle": team.ActiveCycle.ID}, l.maxResults)
+			if err != nil {
+				return "", err
+			}
+			cycle.AtRisk = atRisk(notStarted, cycle.DaysRemaining)
+			if truncated {
+				cycle.Truncated = fmt.Sprintf("only the first %d issues at risk were read, there are more", l.maxResults)
+			}
+		}
+		progress.Cycles = append(progress.Cycles, cycle)
+	}
+
+	jsonResponse, err := json.Marshal(progress)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(jsonResponse), nil
+}
+
+// cycleProgress summarises a cycle, leaving out the issues at risk. Its dates
+// are days in location.
+func cycleProgress(team string, cycle cycleNode, now time.Time, location *time.Location) CycleProgress {
+	progress := CycleProgress{
+		Team:            team,
+		Cycle:           cycle.Name,
+		Start:           cycle.StartsAt.In(location).Format("2006-01-02"),
+		End:             cycle.EndsAt.In(location).Format("2006-01-02"),
+		DaysRemaining:   max(0, int(math.Ceil(cycle.EndsAt.Sub(now).Hours()/24))),
+		ScopePoints:     latest(cycle.ScopeHistory),
+		CompletedPoints: latest(cycle.CompletedScopeHistory),
+		Issues:          int(latest(cycle.IssueCountHistory)),
+		CompletedIssues: int(latest(cycle.CompletedIssueCountHistory)),
+		Progress:        int(math.Round(cycle.Progress * 100)),
+	}
+	if progress.Cycle == "" {
+		progress.Cycle = fmt.Sprintf("Cycle %d", cycle.Number)
+	}
+	return progress
+}
+
+// pastHalfway reports whether half of the cycle has gone, after which issues
+// that haven't been started are at risk.
+func pastHalfway(cycle cycleNode, now time.Time) bool {
+	halfway := cycle.StartsAt.Add(cycle.EndsAt.Sub(cycle.StartsAt) / 2)
+	return !now.Before(halfway)
+}
+
+// atRisk marks the cycle's issues that haven't been started as at risk.
+func atRisk(notStarted []issueNode, daysRemaining int) []AtRiskIssue {
+	issues := make([]AtRiskIssue, 0, len(notStarted))
+	for _, node := range notStarted {
+		issues = append(issues, AtRiskIssue{
+			LinearIssue: node.summary(),
+			Reason:      fmt.Sprintf("not started with %d days left", daysRemaining),
+		})
+	}
+	return issues
+}
+
+// latest is the last value of a Linear history, which has a value per day of
+// the cycle.
+func latest(history []float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	return history[len(history)-1]
+}
+
+// getProjectProgress summarises the teams' planned and started projects with
+// their milestones. Projects shared by teams are listed once.
+func (l *Linear) getProjectProgress(ctx context.Context, teams []string) (string, error) {
+	if err := l.needTeams("get_project_progress", teams); err != nil {
+		return "", err
+	}
+	fmt.Println("Getting project progress.")
+
+	nodes, truncated, err := paginate[projectNode](ctx, l, "projects", `
+	query ProjectProgress($teams: [String!]!, $first: Int!, $after: String) {
+		projects(
+			filter: {
+				accessibleTeams: { some: { key: { in: $teams } } },
+				status: { type: { in: ["planned", "started"] } }
+			},
+			first: $first,
+			after: $after
+		) {
+			nodes {
+				id
+				name
+				url
+				status {
+					name
+					type
+				}
+				lead {
+					name
+				}
+				teams {
+					nodes {
+						key
+						name
+					}
+				}
+				progress
+				targetDate
+				projectMilestones {
+					nodes {
+						name
+						targetDate
+						progress
+					}
+				}
+			}
+			pageInfo {
+				hasNextPage
+				endCursor
+			}
+		}
+	}
+	`, map[string]any{"teams": teams}, l.maxResults)
+	if err != nil {
+		return "", err
+	}
+
+	today := time.Now().In(l.location).Format("2006-01-02")
+	projects := make([]*ProjectProgress, 0, len(nodes))
+	for _, node := range nodes {
+		project := projectProgress(node, today)
+		for _, team := range node.Teams.Nodes {
+			if slices.Contains(teams, team.Key) {
+				project.Teams = append(project.Teams, team.Name)
+			}
+		}
+		projects = append(projects, project)
+	}
+	return marshalResults(projects, truncated, l.maxResults)
+}
+
+// projectProgress summarises a project. Target dates are days, so compare as
+// YYYY-MM-DD strings with today.
+func projectProgress(node projectNode, today string) *ProjectProgress {
+	project := &ProjectProgress{
+		Name:       node.Name,
+		URL:        node.URL,
+		Status:     node.Status.Name,
+		Progress:   int(math.Round(node.Progress * 100)),
+		TargetDate: node.TargetDate,
+		Overdue:    node.TargetDate != "" && node.TargetDate < today,
+	}
+	if node.Lead != nil {
+		project.Lead = node.Lead.Name
+	}
+	for _, milestone := range node.ProjectMilestones.Nodes {
+		project.Milestones = append(project.Milestones, MilestoneSummary{
+			Name:       milestone.Name,
+			TargetDate: milestone.TargetDate,
+			Progress:   int(math.Round(milestone.Progress * 100)),
+			Overdue:    milestone.TargetDate != "" && milestone.TargetDate < today && milestone.Progress < 1,
+		})
+	}
+	return project
+}
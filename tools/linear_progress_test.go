@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func notStartedPage(identifier string, next string) string {
+	return fmt.Sprintf(`{"data":{"issues":{"nodes":[{"identifier":%q,"title":"Not started","state":{"name":"Todo","type":"unstarted"}}],"pageInfo":{"hasNextPage":%v,"endCursor":%q}}}}`, identifier, next != "", next)
+}
+
+func TestGetCycleProgress(t *testing.T) {
+	now := time.Now()
+	cycles := fmt.Sprintf(`{"data":{"teams":{"nodes":[
+		{"key":"ENG","name":"Engineering","activeCycle":{"id":"cycle-1","number":7,"startsAt":%q,"endsAt":%q,"progress":0.5,"issueCountHistory":[4,6]}},
+		{"key":"OPS","name":"Operations","activeCycle":null}
+	]}}}`, now.AddDate(0, 0, -10).Format(time.RFC3339), now.AddDate(0, 0, 4).Format(time.RFC3339))
+
+	tests := []struct {
+		name          string
+		maxResults    int
+		wantAtRisk    []string
+		wantTruncated bool
+	}{
+		{"reads every page", 10, []string{"ENG-1", "ENG-2"}, false},
+		{"stops at the limit", 1, []string{"ENG-1"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := fakeLinearAPI(t, map[string][]string{
+				"query CycleProgress":   {cycles},
+				"query CycleNotStarted": {notStartedPage("ENG-1", "cursor-1"), notStartedPage("ENG-2", "")},
+			})
+			linear := NewLinear("token", test.maxResults, time.UTC, time.Hour, nil)
+
+			output, err := linear.getCycleProgress(context.Background(), []string{"ENG", "OPS"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var progress CyclesProgress
+			if err := json.Unmarshal([]byte(output), &progress); err != nil {
+				t.Fatal(err)
+			}
+			if len(progress.Cycles) != 1 || progress.Cycles[0].Cycle != "Cycle 7" || progress.Cycles[0].Issues != 6 {
+				t.Fatalf("cycles are %+v", progress.Cycles)
+			}
+			if len(progress.NoActiveCycle) != 1 || progress.NoActiveCycle[0] != "Operations" {
+				t.Errorf("no active cycle is %v, want Operations", progress.NoActiveCycle)
+			}
+
+			cycle := progress.Cycles[0]
+			var atRisk []string
+			for _, issue := range cycle.AtRisk {
+				atRisk = append(atRisk, issue.Identifier)
+			}
+			if strings.Join(atRisk, ",") != strings.Join(test.wantAtRisk, ",") {
+				t.Errorf("at risk is %v, want %v", atRisk, test.wantAtRisk)
+			}
+			if (cycle.Truncated != "") != test.wantTruncated {
+				t.Errorf("truncated is %q, want truncated %v", cycle.Truncated, test.wantTruncated)
+			}
+			if !strings.Contains(strings.Join(*requests, "\n"), `"cycle":"cycle-1"`) {
+				t.Errorf("issues were not read from the active cycle:\n%s", strings.Join(*requests, "\n"))
+			}
+		})
+	}
+}
+
+func TestGetCycleProgressBeforeHalfway(t *testing.T) {
+	now := time.Now()
+	requests := fakeLinearAPI(t, map[string][]string{
+		"query CycleProgress": {fmt.Sprintf(`{"data":{"teams":{"nodes":[{"key":"ENG","name":"Engineering","activeCycle":{"id":"cycle-1","number":7,"startsAt":%q,"endsAt":%q}}]}}}`,
+			now.AddDate(0, 0, -1).Format(time.RFC3339), now.AddDate(0, 0, 13).Format(time.RFC3339))},
+	})
+	linear := NewLinear("token", 10, time.UTC, time.Hour, nil)
+
+	output, err := linear.getCycleProgress(context.Background(), []string{"ENG"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output, "at_risk") {
+		t.Errorf("issues are at risk before halfway: %s", output)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("made %d requests, want 1", len(*requests))
+	}
+}
+
+func projectsPageJSON(name string, next string) string {
+	return fmt.Sprintf(`{"data":{"projects":{"nodes":[{"id":%q,"name":%q,"status":{"name":"In Progress","type":"started"},"teams":{"nodes":[{"key":"ENG","name":"Engineering"},{"key":"WEB","name":"Web"},{"key":"OPS","name":"Operations"}]},"progress":0.25,"targetDate":"2000-01-01"}],"pageInfo":{"hasNextPage":%v,"endCursor":%q}}}}`, name, name, next != "", next)
+}
+
+func TestGetProjectProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxResults    int
+		want          []string
+		wantTruncated bool
+	}{
+		{"reads every page", 10, []string{"Search", "Billing"}, false},
+		{"stops at the limit", 1, []string{"Search"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := fakeLinearAPI(t, map[string][]string{
+				"query ProjectProgress": {projectsPageJSON("Search", "cursor-1"), projectsPageJSON("Billing", "")},
+			})
+			linear := NewLinear("token", test.maxResults, time.UTC, time.Hour, nil)
+
+			output, err := linear.getProjectProgress(context.Background(), []string{"ENG", "OPS"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paged struct {
+				Results   []ProjectProgress `json:"results"`
+				Truncated string            `json:"truncated"`
+			}
+			if err := json.Unmarshal([]byte(output), &paged); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, project := range paged.Results {
+				names = append(names, project.Name)
+				if strings.Join(project.Teams, ",") != "Engineering,Operations" {
+					t.Errorf("%s teams are %v, want the configured teams", project.Name, project.Teams)
+				}
+				if !project.Overdue || project.Progress != 25 {
+					t.Errorf("%s is %+v", project.Name, project)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(test.want, ",") {
+				t.Errorf("projects are %v, want %v", names, test.want)
+			}
+			if (paged.Truncated != "") != test.wantTruncated {
+				t.Errorf("truncated is %q, want truncated %v", paged.Truncated, test.wantTruncated)
+			}
+			if !strings.Contains((*requests)[0], `status: { type: { in: [\"planned\", \"started\"] } }`) {
+				t.Errorf("projects are not filtered by status:\n%s", (*requests)[0])
+			}
+			if len(*requests) > 1 && !strings.Contains((*requests)[1], `"after":"cursor-1"`) {
+				t.Errorf("second page is not after the first:\n%s", (*requests)[1])
+			}
+		})
+	}
+}
+
+func TestCycleProgressDates(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cycle := cycleNode{
+		Number: 3,
+		// Monday and the Friday after in Tokyo, still the day before in UTC
+		StartsAt: time.Date(2025, time.January, 5, 15, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2025, time.January, 9, 15, 0, 0, 0, time.UTC),
+	}
+	now := time.Date(2025, time.January, 8, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		location  *time.Location
+		wantStart string
+		wantEnd   string
+	}{
+		{tokyo, "2025-01-06", "2025-01-10"},
+		{time.UTC, "2025-01-05", "2025-01-09"},
+	}
+	for _, test := range tests {
+		t.Run(test.location.String(), func(t *testing.T) {
+			progress := cycleProgress("Engineering", cycle, now, test.location)
+			if progress.Start != test.wantStart || progress.End != test.wantEnd {
+				t.Errorf("cycle runs %s to %s, want %s to %s", progress.Start, progress.End, test.wantStart, test.wantEnd)
+			}
+			if progress.DaysRemaining != 1 {
+				t.Errorf("%d days remaining, want 1", progress.DaysRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// fakeLinearAPI answers each Linear request with the next response for the
+// operation name found in its query, repeating the last one, and records the
+// requests made.
+func fakeLinearAPI(t *testing.T, responses map[string][]string) *[]string {
+	t.Helper()
+	var operations []string
+	transport := http.DefaultClient.Transport
+	t.Cleanup(func() { http.DefaultClient.Transport = transport })
+	http.DefaultClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		for operation, queue := range responses {
+			if !strings.Contains(string(body), operation) {
+				continue
+			}
+			operations = append(operations, string(body))
+			response := queue[0]
+			if len(queue) > 1 {
+				responses[operation] = queue[1:]
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(response)),
+			}, nil
+		}
+		t.Errorf("unexpected request: %s", body)
+		return nil, errors.New("unexpected request")
+	})
+	return &operations
+}
+
+func TestTeamActionsNeedTeams(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+	}{
+		{"missing teams", `{"action":"get_my_teams_in_review_issues"}`},
+		{"empty teams", `{"action":"get_my_teams_in_review_issues","teams":[]}`},
+		{"cycle progress", `{"action":"get_cycle_progress"}`},
+		{"project progress", `{"action":"get_project_progress","teams":[]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := fakeLinearAPI(t, map[string][]string{})
+			linear := NewLinear("token", 10, time.UTC, time.Hour, nil)
+
+			_, err := linear.Run(context.Background(), []byte(test.arguments))
+			var invalid *InvalidToolArgumentsError
+			if !errors.As(err, &invalid) {
+				t.Errorf("error is %v, want invalid arguments", err)
+			}
+			if len(*requests) != 0 {
+				t.Errorf("made %d requests, want none", len(*requests))
+			}
+		})
+	}
+}
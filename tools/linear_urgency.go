@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// MyIssue is an issue assigned to me, with what makes it urgent.
+type MyIssue struct {
+	LinearIssue
+	DueDate string `json:"due_date,omitempty"`
+	// SLABreaches is when the issue breaches its SLA, in my time zone
+	SLABreaches string   `json:"sla_breaches,omitempty"`
+	LastUpdated string   `json:"last_updated"`
+	BlockedBy   []string `json:"blocked_by,omitempty"`
+	Blocking    []string `json:"blocking,omitempty"`
+	// Urgency is overdue, due_today, blocked or stale, most urgent first
+	Urgency []string `json:"urgency,omitempty"`
+}
+
+// urgencies are the kinds of urgency, most urgent first.
+var urgencies = []string{"overdue", "due_today", "blocked", "stale"}
+
+// myIssueFields are issueFields with the fields for working out urgency.
+const myIssueFields = issueFields + `
+	dueDate
+	updatedAt
+	slaBreachesAt
+	relations {
+		nodes {
+			type
+			relatedIssue {
+				identifier
+				state {
+					type
+				}
+			}
+		}
+	}
+	inverseRelations {
+		nodes {
+			type
+			issue {
+				identifier
+				state {
+					type
+				}
+			}
+		}
+	}
+`
+
+// myIssues summarises my issues, most urgent first, then open issues by
+// priority. Issues that are done or cancelled are never urgent and go last.
+func (l *Linear) myIssues(nodes []issueNode, now time.Time) []MyIssue {
+	now = now.In(l.location)
+	today := now.Format("2006-01-02")
+	endOfToday := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, l.location)
+
+	issues := make([]MyIssue, 0, len(nodes))
+	for _, node := range nodes {
+		issue := MyIssue{
+			LinearIssue: node.summary(),
+			DueDate:     node.DueDate,
+			LastUpdated: formatAge(now.Sub(node.UpdatedAt)) + " ago",
+		}
+		if node.SLABreachesAt != nil {
+			issue.SLABreaches = node.SLABreachesAt.In(l.location).Format("2006-01-02 15:04")
+		}
+		for _, relation := range node.InverseRelations.Nodes {
+			if relation.Type == "blocks" && issueOpen(relation.Issue.State.Type) {
+				issue.BlockedBy = append(issue.BlockedBy, relation.Issue.Identifier)
+			}
+		}
+		for _, relation := range node.Relations.Nodes {
+			if relation.Type == "blocks" && issueOpen(relation.RelatedIssue.State.Type) {
+				issue.Blocking = append(issue.Blocking, relation.RelatedIssue.Identifier)
+			}
+		}
+
+		if issueOpen(node.State.Type) {
+			switch {
+			case node.DueDate != "" && node.DueDate < today,
+				node.SLABreachesAt != nil && node.SLABreachesAt.Before(now):
+				issue.Urgency = append(issue.Urgency, "overdue")
+			case node.DueDate == today,
+				node.SLABreachesAt != nil && node.SLABreachesAt.Before(endOfToday):
+				issue.Urgency = append(issue.Urgency, "due_today")
+			}
+			if len(issue.BlockedBy) > 0 {
+				issue.Urgency = append(issue.Urgency, "blocked")
+			}
+			if now.Sub(node.UpdatedAt) >= l.staleAfter {
+				issue.Urgency = append(issue.Urgency, "stale")
+			}
+		}
+		issues = append(issues, issue)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if a, b := urgencyRank(issues[i]), urgencyRank(issues[j]); a != b {
+			return a < b
+		}
+		if a, b := issueOpen(issues[i].StateType), issueOpen(issues[j].StateType); a != b {
+			return a
+		}
+		return priorityRank(issues[i].Priority) < priorityRank(issues[j].Priority)
+	})
+	return issues
+}
+
+// issueOpen reports whether an issue in a state of the type is still to be done.
+func issueOpen(stateType string) bool {
+	return stateType != "completed" && stateType != "canceled"
+}
+
+// urgencyRank is the position of the issue's most urgent urgency, after all of
+// them when it isn't urgent.
+func urgencyRank(issue MyIssue) int {
+	if len(issue.Urgency) == 0 {
+		return len(urgencies)
+	}
+	return slices.Index(urgencies, issue.Urgency[0])
+}
+
+// priorityRank puts issues without a priority after low ones.
+func priorityRank(priority string) int {
+	if priority == "" {
+		return len(linearPriorities)
+	}
+	return slices.Index(linearPriorities, priority)
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// myIssueNodes are my issues as Linear sends them, in no particular order.
+// Now is 08:00 on the 8th in Tokyo, still the 7th in UTC.
+const myIssueNodes = `[
+	{"identifier":"ENG-1","state":{"type":"started"},"priority":2,"updatedAt":"2025-01-07T22:00:00Z"},
+	{"identifier":"ENG-7","state":{"type":"completed"},"priority":1,"dueDate":"2025-01-01","updatedAt":"2024-12-01T00:00:00Z"},
+	{"identifier":"ENG-3","state":{"type":"unstarted"},"priority":3,"dueDate":"2025-01-08","updatedAt":"2025-01-07T22:00:00Z"},
+	{"identifier":"ENG-10","state":{"type":"backlog"},"priority":0,"updatedAt":"2025-01-07T22:00:00Z"},
+	{"identifier":"ENG-5","state":{"type":"started"},"priority":1,"updatedAt":"2025-01-07T22:00:00Z",
+		"relations":{"nodes":[{"type":"blocks","relatedIssue":{"identifier":"ENG-6","state":{"type":"unstarted"}}}]},
+		"inverseRelations":{"nodes":[
+			{"type":"blocks","issue":{"identifier":"ENG-8","state":{"type":"completed"}}},
+			{"type":"related","issue":{"identifier":"ENG-11","state":{"type":"started"}}}
+		]}},
+	{"identifier":"ENG-6","state":{"type":"unstarted"},"priority":0,"updatedAt":"2024-12-20T00:00:00Z"},
+	{"identifier":"ENG-4","state":{"type":"started"},"priority":1,"slaBreachesAt":"2025-01-08T11:00:00Z","updatedAt":"2024-12-28T00:00:00Z",
+		"inverseRelations":{"nodes":[{"type":"blocks","issue":{"identifier":"ENG-9","state":{"type":"started"}}}]}},
+	{"identifier":"ENG-2","state":{"type":"unstarted"},"priority":4,"dueDate":"2025-01-07","updatedAt":"2025-01-07T22:00:00Z"}
+]`
+
+func TestMyIssues(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes []issueNode
+	if err := json.Unmarshal([]byte(myIssueNodes), &nodes); err != nil {
+		t.Fatal(err)
+	}
+	linear := NewLinear("token", 10, tokyo, 7*24*time.Hour, nil)
+	issues := linear.myIssues(nodes, time.Date(2025, time.January, 7, 23, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		identifier  string
+		urgency     []string
+		slaBreaches string
+		blockedBy   []string
+		blocking    []string
+		lastUpdated string
+	}{
+		{identifier: "ENG-2", urgency: []string{"overdue"}, lastUpdated: "1h ago"},
+		{identifier: "ENG-4", urgency: []string{"due_today", "blocked", "stale"}, slaBreaches: "2025-01-08 20:00", blockedBy: []string{"ENG-9"}, lastUpdated: "10d ago"},
+		{identifier: "ENG-3", urgency: []string{"due_today"}, lastUpdated: "1h ago"},
+		{identifier: "ENG-6", urgency: []string{"stale"}, lastUpdated: "18d ago"},
+		{identifier: "ENG-5", blocking: []string{"ENG-6"}, lastUpdated: "1h ago"},
+		{identifier: "ENG-1", lastUpdated: "1h ago"},
+		{identifier: "ENG-10", lastUpdated: "1h ago"},
+		{identifier: "ENG-7", lastUpdated: "37d ago"},
+	}
+	if len(issues) != len(tests) {
+		t.Fatalf("got %d issues, want %d", len(issues), len(tests))
+	}
+	for i, test := range tests {
+		t.Run(test.identifier, func(t *testing.T) {
+			got := issues[i]
+			if got.Identifier != test.identifier {
+				t.Fatalf("issue %d is %s, want %s", i, got.Identifier, test.identifier)
+			}
+			if !reflect.DeepEqual(got.Urgency, test.urgency) {
+				t.Errorf("urgency is %v, want %v", got.Urgency, test.urgency)
+			}
+			if got.SLABreaches != test.slaBreaches {
+				t.Errorf("SLA breaches %q, want %q", got.SLABreaches, test.slaBreaches)
+			}
+			if !reflect.DeepEqual(got.BlockedBy, test.blockedBy) || !reflect.DeepEqual(got.Blocking, test.blocking) {
+				t.Errorf("blocked by %v and blocking %v, want %v and %v", got.BlockedBy, got.Blocking, test.blockedBy, test.blocking)
+			}
+			if got.LastUpdated != test.lastUpdated {
+				t.Errorf("last updated %q, want %q", got.LastUpdated, test.lastUpdated)
+			}
+		})
+	}
+}
+
+func TestGetMyIssuesKeepsTheMostUrgent(t *testing.T) {
+	fakeLinearAPI(t, map[string][]string{
+		"query Viewer": {`{"data":{"viewer":{"id":"me"}}}`},
+		// Linear orders by when the issue was updated, the overdue issue last
+		"query MyIssues": {`{"data":{"issues":{"nodes":[
+			{"identifier":"ENG-1","state":{"type":"started"},"priority":1,"updatedAt":"` + time.Now().Format(time.RFC3339) + `"},
+			{"identifier":"ENG-2","state":{"type":"started"},"priority":1,"updatedAt":"` + time.Now().Format(time.RFC3339) + `"},
+			{"identifier":"ENG-3","state":{"type":"unstarted"},"priority":4,"dueDate":"2000-01-01","updatedAt":"` + time.Now().Format(time.RFC3339) + `"}
+		],"pageInfo":{"hasNextPage":false}}}}`},
+	})
+	linear := NewLinear("token", 2, time.UTC, 7*24*time.Hour, nil)
+
+	output, err := linear.getMyIssues(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paged struct {
+		Results   []MyIssue `json:"results"`
+		Truncated string    `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(output), &paged); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, issue := range paged.Results {
+		got = append(got, issue.Identifier)
+	}
+	if !reflect.DeepEqual(got, []string{"ENG-3", "ENG-1"}) {
+		t.Errorf("issues are %v, want [ENG-3 ENG-1]", got)
+	}
+	if paged.Truncated != "only the first 2 results were read, there are more" {
+		t.Errorf("truncated is %q", paged.Truncated)
+	}
+}
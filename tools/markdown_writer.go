@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/anthropics/anthropic-sdk-go"
 )
@@ -12,8 +13,8 @@ type MarkdownWriter struct {
 }
 
 type Section struct {
-	Title    string   `json:"title" jsonschema_description:"The title of the section e.g. My PRs"`
-	Content  string   `json:"content" jsonschema_description:"The content of the section"`
+	Title    string   `json:"title" jsonschema_description:"The title of the section e.g. Calendar, without the markdown heading"`
+	Content  string   `json:"content" jsonschema_description:"The markdown content of the section, without its heading"`
 	Comments []string `json:"comments" jsonschema_description:"The comments to write to the section"`
 }
 
@@ -28,21 +29,38 @@ type MarkdownWriterInputs struct {
 }
 
 func (m *MarkdownWriter) Run(ctx context.Context, arguments json.RawMessage) (string, error) {
-
-	var section Section
-	if err := json.Unmarshal(arguments, &section); err != nil {
+	var inputs MarkdownWriterInputs
+	if err := json.Unmarshal(arguments, &inputs); err != nil {
 		return "", &InvalidToolArgumentsError{
 			ToolName: m.Name(),
 			Message:  "invalid JSON format",
 		}
 	}
+	if len(inputs.Sections) == 0 {
+		return "", &InvalidToolArgumentsError{
+			ToolName: m.Name(),
+			Message:  "at least one section is required",
+		}
+	}
 
-	select {
-	case m.write <- section:
-		return "Section written successfully", nil
-	case <-ctx.Done():
-		return "", ctx.Err()
+	// Check every section before writing any, so a retry after an error
+	// doesn't write the first sections twice
+	for _, section := range inputs.Sections {
+		if section.Title == "" {
+			return "", &InvalidToolArgumentsError{
+				ToolName: m.Name(),
+				Message:  "every section needs a title",
+			}
+		}
+	}
+	for _, section := range inputs.Sections {
+		select {
+		case m.write <- section:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
 	}
+	return fmt.Sprintf("%d sections written successfully", len(inputs.Sections)), nil
 }
 
 func (m *MarkdownWriter) Name() string {
@@ -52,7 +70,7 @@ func (m *MarkdownWriter) Name() string {
 func (m *MarkdownWriter) ToolDefinition() *anthropic.ToolParam {
 	return &anthropic.ToolParam{
 		Name:        m.Name(),
-		Description: anthropic.String("Write a section to the markdown document"),
+		Description: anthropic.String("Write sections to the markdown document. Writing a section again replaces it"),
 		InputSchema: GenerateSchema[MarkdownWriterInputs](),
 	}
 }
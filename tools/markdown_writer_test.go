@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMarkdownWriterChecksEverySectionFirst(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		wantErr   bool
+		want      []string
+	}{
+		{"writes every section", `{"sections":[{"title":"Calendar","content":"- 09:00 Standup"},{"title":"Todo","content":"- Review"}]}`, false, []string{"Calendar", "Todo"}},
+		{"writes nothing when a later section has no title", `{"sections":[{"title":"Calendar","content":"- 09:00 Standup"},{"content":"- Review"}]}`, true, nil},
+		{"needs a section", `{"sections":[]}`, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := make(chan Section, 2)
+			_, err := NewMarkdownWriter(sections).Run(context.Background(), []byte(test.arguments))
+			var invalid *InvalidToolArgumentsError
+			if test.wantErr != errors.As(err, &invalid) {
+				t.Fatalf("error is %v, want invalid arguments %v", err, test.wantErr)
+			}
+			close(sections)
+			var got []string
+			for section := range sections {
+				got = append(got, section.Title)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("wrote %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("wrote %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
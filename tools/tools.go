@@ -56,3 +56,22 @@ type InvalidToolArgumentsError struct {
 func (e *InvalidToolArgumentsError) Error() string {
 	return fmt.Sprintf("invalid arguments for tool %s: %s", e.ToolName, e.Message)
 }
+
+// pagedResults is the output of actions that list things. Truncated says so
+// when there were more results than the limit.
+type pagedResults struct {
+	Results   any    `json:"results"`
+	Truncated string `json:"truncated,omitempty"`
+}
+
+func marshalResults(results any, truncated bool, limit int) (string, error) {
+	paged := pagedResults{Results: results}
+	if truncated {
+		paged.Truncated = fmt.Sprintf("only the first %d results were read, there are more", limit)
+	}
+	jsonResponse, err := json.Marshal(paged)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+	return string(jsonResponse), nil
+}
@@ -0,0 +1,201 @@
+package watcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gabe-mason/good-morning/agent"
+	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/document"
+	"github.com/gabe-mason/good-morning/tools"
+)
+
+// settle is how long notes must stay unchanged before they are read, so
+// action items are not extracted from half written notes.
+const settle = time.Minute
+
+// Watcher looks for edits to the meeting notes in today's summary and turns
+// them into action items. It polls the file rather than watching for writes:
+// notes are only read once they have settled for a minute anyway, a poll is a
+// single small read, and it works the same for editors that save by replacing
+// the file and for synced folders, without another dependency.
+type Watcher struct {
+	cfg      *config.Config
+	provider agent.Provider
+	linear   *tools.Linear
+
+	date      string
+	processed map[string]string
+	pending   map[string]pendingNote
+}
+
+type pendingNote struct {
+	hash  string
+	since time.Time
+}
+
+// NewWatcher creates a watcher. The linear tool creates an issue for each new
+// action item when create_issue is allowed, and may be nil.
+func NewWatcher(cfg *config.Config, provider agent.Provider, linear *tools.Linear) *Watcher {
+	return &Watcher{
+		cfg:      cfg,
+		provider: provider,
+		linear:   linear,
+	}
+}
+
+// Run checks the notes every interval until the context is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.NotesInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+func (w *Watcher) check(ctx context.Context) {
+	if date := time.Now().In(w.cfg.Location).Format("2006-01-02"); date != w.date {
+		w.date = date
+		w.pending = make(map[string]pendingNote)
+		w.processed = w.loadState()
+	}
+
+	summary, err := os.ReadFile(w.cfg.GetSummaryLocation())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading summary: %v", err)
+		}
+		return
+	}
+
+	notes := document.Parse(string(summary)).Notes(agent.CalendarSection.Heading)
+	for meeting, text := range notes {
+		hash := hashNotes(text)
+		if w.processed[meeting] == hash {
+			delete(w.pending, meeting)
+			continue
+		}
+		pending, ok := w.pending[meeting]
+		if !ok || pending.hash != hash {
+			w.pending[meeting] = pendingNote{hash: hash, since: time.Now()}
+			continue
+		}
+		if time.Since(pending.since) < settle {
+			continue
+		}
+
+		if err := w.process(ctx, meeting, text); err != nil {
+			log.Printf("Error reading notes for %s: %v", meeting, err)
+			continue
+		}
+		delete(w.pending, meeting)
+		w.processed[meeting] = hash
+		w.saveState()
+	}
+}
+
+// process extracts the action items from a meeting's notes and adds the new
+// ones to the summary. Items already listed are skipped, so ticking one off
+// or editing the notes again does not duplicate it.
+func (w *Watcher) process(ctx context.Context, meeting string, notes string) error {
+	title := meetingTitle(meeting)
+	fmt.Println("Reading your notes from " + title + ".")
+	items, err := agent.NewNotesAgent(w.provider, w.cfg).ExtractActionItems(ctx, title, notes)
+	if err != nil {
+		return err
+	}
+
+	added := make([]agent.ActionItem, 0)
+	err = document.Patch(w.cfg.GetSummaryLocation(), func(doc *document.Document) error {
+		doc.AddSection(agent.ActionItemsSection.Heading)
+		for _, item := range items {
+			key := fmt.Sprintf("**%s**: %s", item.Owner, item.Action)
+			if doc.Contains(agent.ActionItemsSection.Heading, key) {
+				continue
+			}
+			doc.Upsert(agent.ActionItemsSection.Heading, key, fmt.Sprintf("- [ ] %s _(%s)_", key, title))
+			added = append(added, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if w.linear != nil {
+		for _, item := range added {
+			w.createIssue(ctx, title, item)
+		}
+	}
+	return nil
+}
+
+// createIssue creates an issue for the action item through the Linear tool's
+// create_issue action, so it is only made when the confirmation gate allows
+// it.
+func (w *Watcher) createIssue(ctx context.Context, meeting string, item agent.ActionItem) {
+	teams := w.cfg.Teams()
+	if len(teams) == 0 {
+		return
+	}
+	arguments, err := json.Marshal(tools.LinearToolInputs{
+		Action:      "create_issue",
+		Teams:       teams[:1],
+		Title:       item.Action,
+		Description: fmt.Sprintf("Action item for %s from the %s meeting on %s.", item.Owner, meeting, w.date),
+	})
+	if err != nil {
+		return
+	}
+	if _, err := w.linear.Run(ctx, arguments); err != nil {
+		log.Printf("Error creating Linear issue for %q: %v", item.Action, err)
+	}
+}
+
+func (w *Watcher) loadState() map[string]string {
+	processed := make(map[string]string)
+	data, err := os.ReadFile(w.cfg.GetNotesStateLocation())
+	if err != nil {
+		return processed
+	}
+	if err := json.Unmarshal(data, &processed); err != nil {
+		log.Printf("Error reading notes state: %v", err)
+	}
+	return processed
+}
+
+func (w *Watcher) saveState() {
+	data, err := json.MarshalIndent(w.processed, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(w.cfg.GetNotesStateLocation(), data, 0644); err != nil {
+		log.Printf("Error writing notes state: %v", err)
+	}
+}
+
+func hashNotes(notes string) string {
+	sum := sha256.Sum256([]byte(notes))
+	return hex.EncodeToString(sum[:])
+}
+
+// meetingTitle strips the markdown and time from a meeting heading,
+// "### 🧑‍💻 09:30 | Standup" becomes "Standup".
+func meetingTitle(heading string) string {
+	if i := strings.LastIndex(heading, "|"); i >= 0 {
+		return strings.TrimSpace(heading[i+1:])
+	}
+	return strings.TrimSpace(strings.TrimLeft(heading, "# "))
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gabe-mason/good-morning/agent"
+	"github.com/gabe-mason/good-morning/document"
+	"github.com/google/go-github/v70/github"
+)
+
+func (s *Server) handleGithub(w http.ResponseWriter, r *http.Request) {
+	payload, err := github.ValidatePayload(r, []byte(s.cfg.GithubWebhookSecret))
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	pullRequestEvent, ok := event.(*github.PullRequestEvent)
+	if !ok || pullRequestEvent.GetPullRequest() == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	me, err := s.github.Login(r.Context())
+	if err != nil {
+		log.Printf("Error finding the GitHub user: %v", err)
+		http.Error(w, "failed to find the GitHub user", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("GitHub says %s#%d changed.\n", pullRequestEvent.GetRepo().GetFullName(), pullRequestEvent.GetPullRequest().GetNumber())
+	s.patch(w, func(doc *document.Document) error {
+		applyPullRequestEvent(doc, pullRequestEvent, me)
+		return nil
+	})
+}
+
+// applyPullRequestEvent adds pull requests waiting on my review to the review
+// section, and moves them out once they are closed or no longer need me. me is
+// my GitHub login.
+func applyPullRequestEvent(doc *document.Document, event *github.PullRequestEvent, me string) {
+	pr := event.GetPullRequest()
+	key := "(" + pr.GetHTMLURL() + ")"
+	link := fmt.Sprintf("[%s#%d](%s)", event.GetRepo().GetFullName(), pr.GetNumber(), pr.GetHTMLURL())
+
+	switch event.GetAction() {
+	case "review_requested":
+		if event.GetRequestedReviewer().GetLogin() == me {
+			doc.Upsert(agent.ReviewSection.Heading, key, fmt.Sprintf("1. %s - %s (%s)", link, pr.GetTitle(), pr.GetUser().GetLogin()))
+		}
+	case "review_request_removed":
+		if event.GetRequestedReviewer().GetLogin() == me {
+			doc.Remove(agent.ReviewSection.Heading, key)
+		}
+	case "closed":
+		doc.Remove(agent.ReviewSection.Heading, key)
+		if pr.GetMerged() && pr.GetUser().GetLogin() == me {
+			doc.Upsert(agent.TodoSection.Heading, key, fmt.Sprintf("- 🎉 %s - %s(merged)", link, pr.GetTitle()))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gabe-mason/good-morning/agent"
+	"github.com/gabe-mason/good-morning/document"
+)
+
+type linearEvent struct {
+	Action string     `json:"action"`
+	Type   string     `json:"type"`
+	Data   linearData `json:"data"`
+}
+
+type linearData struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Priority   int    `json:"priority"`
+	State      struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"state"`
+	Assignee *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Team struct {
+		Key string `json:"key"`
+	} `json:"team"`
+}
+
+func (s *Server) handleLinear(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if !validLinearSignature(r.Header.Get("Linear-Signature"), payload, []byte(s.cfg.LinearWebhookSecret)) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event linearEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if event.Type != "Issue" || event.Data.Identifier == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	me, err := s.linear.ViewerID(r.Context())
+	if err != nil {
+		log.Printf("Error finding the Linear user: %v", err)
+		http.Error(w, "failed to find the Linear user", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Printf("Linear says %s changed.\n", event.Data.Identifier)
+	s.patch(w, func(doc *document.Document) error {
+		applyLinearEvent(doc, event, me, s.cfg.Teams())
+		return nil
+	})
+}
+
+// validLinearSignature checks the hex encoded HMAC-SHA256 of the body that
+// Linear sends in the Linear-Signature header.
+func validLinearSignature(signature string, payload []byte, secret []byte) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// applyLinearEvent keeps the issue's line in the review and todo sections in
+// step with its state and assignee. Issues are mine when they are assigned to
+// the user with the ID me.
+func applyLinearEvent(doc *document.Document, event linearEvent, me string, teams []string) {
+	issue := event.Data
+	key := "[" + issue.Identifier + "]"
+	assignee := ""
+	mine := false
+	if issue.Assignee != nil {
+		assignee = issue.Assignee.Name
+		mine = issue.Assignee.ID == me
+	}
+	done := issue.State.Type == "completed" || issue.State.Type == "canceled"
+
+	inReview := event.Action != "remove" &&
+		issue.State.Name == "In Review" &&
+		!mine &&
+		slices.Contains(teams, issue.Team.Key)
+	if inReview {
+		doc.Upsert(agent.ReviewSection.Heading, key, fmt.Sprintf("1. [%s](%s) - %s (%s)", issue.Identifier, issue.URL, issue.Title, assignee))
+	} else {
+		doc.Remove(agent.ReviewSection.Heading, key)
+	}
+
+	if event.Action != "remove" && mine && !done {
+		doc.Upsert(agent.TodoSection.Heading, key, fmt.Sprintf("- %s [%s](%s) - %s(%s)", priorityEmoji(issue.Priority), issue.Identifier, issue.URL, issue.Title, issue.State.Name))
+	} else {
+		doc.Remove(agent.TodoSection.Heading, key)
+	}
+}
+
+func priorityEmoji(priority int) string {
+	switch priority {
+	case 1:
+		return "🔥"
+	case 2:
+		return "🔴"
+	case 3:
+		return "🟡"
+	case 4:
+		return "🟢"
+	default:
+		return "⚪"
+	}
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gabe-mason/good-morning/config"
+	"github.com/gabe-mason/good-morning/document"
+)
+
+// LinearViewer finds the Linear user the token belongs to, which the Linear
+// tool does.
+type LinearViewer interface {
+	ViewerID(ctx context.Context) (string, error)
+}
+
+// GithubUser finds the GitHub user the token belongs to, which the GitHub tool
+// does.
+type GithubUser interface {
+	Login(ctx context.Context) (string, error)
+}
+
+// Server receives Linear and GitHub webhooks and patches today's summary, so
+// it stays current between refreshes without polling.
+type Server struct {
+	cfg    *config.Config
+	linear LinearViewer
+	github GithubUser
+	server *http.Server
+}
+
+// NewServer creates the webhook server. Linear issues are mine when they are
+// assigned to the Linear viewer, and pull requests when they involve the
+// GitHub user.
+func NewServer(cfg *config.Config, linear LinearViewer, github GithubUser) *Server {
+	s := &Server{cfg: cfg, linear: linear, github: github}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/linear", s.handleLinear)
+	mux.HandleFunc("POST /webhooks/github", s.handleGithub)
+	s.server = &http.Server{
+		Addr:              cfg.WebhookAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handler exposes the routes, for serving them from another server.
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Run serves webhooks until the context is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		fmt.Printf("Listening for webhooks on %s.\n", s.cfg.WebhookAddr)
+		errs <- s.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// patch applies a change to today's summary. Events that arrive before the
+// briefing has been written are dropped, the briefing will pick them up.
+func (s *Server) patch(w http.ResponseWriter, change func(doc *document.Document) error) {
+	location := s.cfg.GetSummaryLocation()
+	if _, err := os.Stat(location); os.IsNotExist(err) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err := document.Patch(location, change); err != nil {
+		log.Printf("Error patching summary from webhook: %v", err)
+		http.Error(w, "failed to update summary", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
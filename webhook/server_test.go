@@ -0,0 +1,179 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabe-mason/good-morning/config"
+)
+
+const (
+	linearSecret = "linear-secret"
+	githubSecret = "github-secret"
+)
+
+const summary = `# Good Morning Ada!
+
+## Things I need to review 👀
+
+1. [ENG-1](https://linear.app/example/issue/ENG-1) - Older review (Grace Hopper)
+
+## Things I need to do ✅
+
+Active issues assigned to you:
+
+## Suggestions 💡
+-- Add suggestions for my day here
+`
+
+type fakeLinear struct{ id string }
+
+func (f fakeLinear) ViewerID(ctx context.Context) (string, error) {
+	return f.id, nil
+}
+
+type fakeGithub struct{ login string }
+
+func (f fakeGithub) Login(ctx context.Context) (string, error) {
+	return f.login, nil
+}
+
+// newTestServer writes today's summary under a temporary home and returns the
+// webhook routes with the summary's path.
+func newTestServer(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{
+		GoodMorningRoot:     "good-morning",
+		MyName:              "Ada Lovelace",
+		LinearTeams:         "ENG",
+		Location:            time.UTC,
+		LinearWebhookSecret: linearSecret,
+		GithubWebhookSecret: githubSecret,
+	}
+	path := cfg.GetSummaryLocation()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(summary), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(cfg, fakeLinear{id: "user-me"}, fakeGithub{login: "me"}).Handler(), path
+}
+
+func payload(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func linearRequest(body []byte, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/linear", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Linear-Signature", signature)
+	return r
+}
+
+func githubRequest(body []byte, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", "pull_request")
+	r.Header.Set("X-Hub-Signature-256", "sha256="+signature)
+	return r
+}
+
+// addLine is the summary with a line added after the one ending in after.
+func addLine(summary string, after string, line string) string {
+	return strings.Replace(summary, after, after+line+"\n", 1)
+}
+
+func TestWebhooks(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    func(body []byte) *http.Request
+		payload    string
+		wantStatus int
+		// want is the summary afterwards
+		want string
+	}{
+		{
+			name:       "linear issue in review",
+			request:    func(body []byte) *http.Request { return linearRequest(body, sign(linearSecret, body)) },
+			payload:    "linear_issue_in_review.json",
+			wantStatus: http.StatusNoContent,
+			// Someone else with my name is not me
+			want: addLine(summary, "(Grace Hopper)\n", "1. [ENG-42](https://linear.app/example/issue/ENG-42/cache-the-team-list) - Cache the team list (Ada Lovelace)"),
+		},
+		{
+			name:       "linear issue assigned to me",
+			request:    func(body []byte) *http.Request { return linearRequest(body, sign(linearSecret, body)) },
+			payload:    "linear_issue_assigned.json",
+			wantStatus: http.StatusNoContent,
+			want:       addLine(summary, "Active issues assigned to you:\n", "- 🔥 [ENG-43](https://linear.app/example/issue/ENG-43/fix-the-flaky-login-test) - Fix the flaky login test(In Progress)"),
+		},
+		{
+			name:       "linear bad signature",
+			request:    func(body []byte) *http.Request { return linearRequest(body, sign("wrong", body)) },
+			payload:    "linear_issue_in_review.json",
+			wantStatus: http.StatusUnauthorized,
+			want:       summary,
+		},
+		{
+			name:       "github review requested",
+			request:    func(body []byte) *http.Request { return githubRequest(body, sign(githubSecret, body)) },
+			payload:    "github_review_requested.json",
+			wantStatus: http.StatusNoContent,
+			want:       addLine(summary, "(Grace Hopper)\n", "1. [example/service#7](https://github.com/example/service/pull/7) - Add retries to the sync job (grace)"),
+		},
+		{
+			name:       "github merged",
+			request:    func(body []byte) *http.Request { return githubRequest(body, sign(githubSecret, body)) },
+			payload:    "github_merged.json",
+			wantStatus: http.StatusNoContent,
+			want:       addLine(summary, "Active issues assigned to you:\n", "- 🎉 [example/service#8](https://github.com/example/service/pull/8) - Upgrade the Go toolchain(merged)"),
+		},
+		{
+			name:       "github bad signature",
+			request:    func(body []byte) *http.Request { return githubRequest(body, sign("wrong", body)) },
+			payload:    "github_review_requested.json",
+			wantStatus: http.StatusUnauthorized,
+			want:       summary,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, path := newTestServer(t)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, test.request(payload(t, test.payload)))
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("status is %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+
+			written, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(written) != test.want {
+				t.Errorf("summary is:\n%s\nwant:\n%s", written, test.want)
+			}
+		})
+	}
+}